@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"custom-scheduler/pkg/plugins/compatibilityPlugin"
+
+	"github.com/spf13/cobra"
+)
+
+// newDecisionsCommand returns the command printing the compatibility
+// decisions retained by the scheduler, with the node features they used.
+func newDecisionsCommand() *cobra.Command {
+	var (
+		schedURL string
+		pod      string
+		output   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "decisions",
+		Short: "Print the last compatibility decisions of the scheduler with the node features they were made from",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			records, err := fetchDecisions(cmd.Context(), schedURL, pod)
+			if err != nil {
+				return err
+			}
+
+			switch output {
+			case "json":
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(records)
+			case "text":
+				for _, rec := range records {
+					fmt.Printf("%s %s (%s): images %s\n", rec.DecidedAt.Format(time.RFC3339), rec.Pod, rec.Source, strings.Join(rec.Images, ", "))
+					fmt.Printf("  Compatible nodes (%d): %s\n", len(rec.CompatibleNodes), strings.Join(rec.CompatibleNodes, ", "))
+					fmt.Printf("  Incompatible nodes (%d): %s\n", len(rec.IncompatibleNodes), strings.Join(rec.IncompatibleNodes, ", "))
+					fmt.Printf("  Node feature snapshots: %d (use -o json to print them)\n", len(rec.Features))
+				}
+				return nil
+			default:
+				return fmt.Errorf("unsupported output format %q, must be text or json", output)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&schedURL, "scheduler-url", "", "Base URL of the scheduler debug endpoints (plugin bindAddress)")
+	cmd.Flags().StringVar(&pod, "pod", "", "Only print the decisions of this pod, as namespace/name")
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "Output format: text or json")
+	_ = cmd.MarkFlagRequired("scheduler-url")
+
+	return cmd
+}
+
+// fetchDecisions returns the scheduler's retained decisions from its
+// /debug/decisions endpoint.
+func fetchDecisions(ctx context.Context, schedURL, pod string) ([]compatibilityPlugin.DecisionRecord, error) {
+	reqURL := strings.TrimSuffix(schedURL, "/") + "/debug/decisions?pod=" + url.QueryEscape(pod)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scheduler decisions: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to query scheduler decisions: %s", resp.Status)
+	}
+
+	var records []compatibilityPlugin.DecisionRecord
+	if err := json.NewDecoder(resp.Body).Decode(&records); err != nil {
+		return nil, fmt.Errorf("failed to decode scheduler decisions: %w", err)
+	}
+	return records, nil
+}
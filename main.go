@@ -12,6 +12,9 @@ func main() {
 	command := app.NewSchedulerCommand(
 		app.WithPlugin(compatibilityPlugin.PluginName, compatibilityPlugin.New),
 	)
+	command.AddCommand(newSimulateCommand())
+	command.AddCommand(newRBACCommand())
+	command.AddCommand(newDecisionsCommand())
 
 	code := cli.Run(command)
 	os.Exit(code)
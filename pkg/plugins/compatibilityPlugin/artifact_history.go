@@ -0,0 +1,84 @@
+package compatibilityPlugin
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
+)
+
+// ArtifactRecord is the compatibility rules fetched for an image, as they
+// were turned into NodeFeatureGroups.
+type ArtifactRecord struct {
+	Image     string                             `json:"image"`
+	FetchedAt time.Time                          `json:"fetchedAt"`
+	Groups    []nfdv1alpha1.NodeFeatureGroupSpec `json:"groups"`
+}
+
+// artifactHistory keeps the records of the most recently fetched images,
+// evicting the least recently fetched beyond its capacity. A nil history
+// records nothing.
+type artifactHistory struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // Most recently fetched first
+	items    map[string]*list.Element
+}
+
+// newArtifactHistory creates a history of at most capacity images, or nil if
+// capacity is not positive.
+func newArtifactHistory(capacity int) *artifactHistory {
+	if capacity <= 0 {
+		return nil
+	}
+	return &artifactHistory{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// record stores the groups fetched for image.
+func (h *artifactHistory) record(image string, groups []nfdv1alpha1.NodeFeatureGroup) {
+	if h == nil {
+		return
+	}
+	rec := &ArtifactRecord{Image: image, FetchedAt: time.Now(), Groups: make([]nfdv1alpha1.NodeFeatureGroupSpec, 0, len(groups))}
+	for _, group := range groups {
+		rec.Groups = append(rec.Groups, *group.Spec.DeepCopy())
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if elem, ok := h.items[image]; ok {
+		elem.Value = rec
+		h.order.MoveToFront(elem)
+		return
+	}
+	h.items[image] = h.order.PushFront(rec)
+	for h.order.Len() > h.capacity {
+		oldest := h.order.Back()
+		h.order.Remove(oldest)
+		delete(h.items, oldest.Value.(*ArtifactRecord).Image)
+	}
+}
+
+// records returns the records, most recent first, or only the one of image
+// if it is not empty.
+func (h *artifactHistory) records(image string) []ArtifactRecord {
+	records := []ArtifactRecord{}
+	if h == nil {
+		return records
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for elem := h.order.Front(); elem != nil; elem = elem.Next() {
+		rec := elem.Value.(*ArtifactRecord)
+		if image == "" || rec.Image == image {
+			records = append(records, *rec)
+		}
+	}
+	return records
+}
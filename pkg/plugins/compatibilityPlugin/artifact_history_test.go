@@ -0,0 +1,39 @@
+package compatibilityPlugin
+
+import (
+	"testing"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
+)
+
+func TestArtifactHistory(t *testing.T) {
+	groups := []nfdv1alpha1.NodeFeatureGroup{{
+		Spec: nfdv1alpha1.NodeFeatureGroupSpec{Rules: []nfdv1alpha1.GroupRule{{Name: "kernel"}}},
+	}}
+
+	h := newArtifactHistory(2)
+	h.record("a:v1", groups)
+	h.record("b:v1", groups)
+	h.record("a:v1", groups)
+	h.record("c:v1", groups)
+
+	var images []string
+	for _, rec := range h.records("") {
+		images = append(images, rec.Image)
+	}
+	if len(images) != 2 || images[0] != "c:v1" || images[1] != "a:v1" {
+		t.Errorf("expected least recently fetched image evicted, got %v", images)
+	}
+
+	recs := h.records("a:v1")
+	if len(recs) != 1 || len(recs[0].Groups) != 1 || recs[0].Groups[0].Rules[0].Name != "kernel" {
+		t.Errorf("unexpected record %+v", recs)
+	}
+
+	// Disabled history records nothing
+	disabled := newArtifactHistory(0)
+	disabled.record("a:v1", groups)
+	if recs := disabled.records(""); len(recs) != 0 {
+		t.Errorf("expected no records, got %v", recs)
+	}
+}
@@ -0,0 +1,109 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// DefaultCacheFlushInterval is how often the cache is written to CacheFile
+// when CacheFlushInterval is not set.
+const DefaultCacheFlushInterval = time.Minute
+
+// cacheFlushInterval returns the configured cache flush interval, or the default.
+func cacheFlushInterval(args ImageCompatibilityPluginArgs) time.Duration {
+	if args.CacheFlushInterval.Duration > 0 {
+		return args.CacheFlushInterval.Duration
+	}
+	return DefaultCacheFlushInterval
+}
+
+// saveCacheFile writes the cache entries to path. The file is replaced
+// atomically so a crash while writing does not corrupt the previous one.
+func (f *ImageCompatibilityPlugin) saveCacheFile(path string) error {
+	data, err := json.Marshal(f.cacheEntries(""))
+	if err != nil {
+		return fmt.Errorf("failed to encode cache: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write cache file %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache file %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache file %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write cache file %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadCacheFile restores the cache entries written to path by a previous
+// run, skipping the expired ones. A missing or unreadable file leaves the
+// cache empty. The NFGs of restored entries are still verified before reuse.
+func (f *ImageCompatibilityPlugin) loadCacheFile(path string) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to read cache file %s, starting with an empty cache: %v", path, err)
+		return
+	}
+	var entries []CacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		log.Printf("Failed to decode cache file %s, starting with an empty cache: %v", path, err)
+		return
+	}
+
+	now := time.Now()
+	loaded := 0
+	for _, e := range entries {
+		entry := nfgCacheEntry{
+			nfgNames:       slices.Clone(e.NodeFeatureGroups),
+			noRequirements: e.NoRequirements,
+			cachedAt:       e.CachedAt,
+		}
+		if e.ExpiresAt != nil {
+			if now.After(*e.ExpiresAt) {
+				continue
+			}
+			entry.expiresAt = *e.ExpiresAt
+		}
+		f.imageToNFGCache.set(e.Image, entry)
+		loaded++
+	}
+	log.Printf("Loaded %d of %d cache entries from %s", loaded, len(entries), path)
+}
+
+// runCachePersistence writes the cache to path every interval, and once more
+// when ctx is done.
+func (f *ImageCompatibilityPlugin) runCachePersistence(ctx context.Context, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := f.saveCacheFile(path); err != nil {
+				log.Printf("Failed to persist cache on shutdown: %v", err)
+			}
+			return
+		case <-ticker.C:
+			if err := f.saveCacheFile(path); err != nil {
+				log.Printf("Failed to persist cache: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package compatibilityPlugin
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCacheFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	now := time.Now()
+	saved := &ImageCompatibilityPlugin{imageToNFGCache: newNFGCache()}
+	saved.imageToNFGCache.set("app:v1", nfgCacheEntry{nfgNames: []string{"nfg-a", "nfg-b"}, cachedAt: now, expiresAt: now.Add(time.Hour)})
+	saved.imageToNFGCache.set("app:v2", nfgCacheEntry{noRequirements: true, cachedAt: now})
+	saved.imageToNFGCache.set("app:old", nfgCacheEntry{nfgNames: []string{"nfg-c"}, cachedAt: now.Add(-2 * time.Hour), expiresAt: now.Add(-time.Hour)})
+	if err := saved.saveCacheFile(path); err != nil {
+		t.Fatalf("saveCacheFile: %v", err)
+	}
+
+	loaded := &ImageCompatibilityPlugin{imageToNFGCache: newNFGCache()}
+	loaded.loadCacheFile(path)
+
+	entry, found := loaded.imageToNFGCache.get("app:v1")
+	if !found || !slices.Equal(entry.nfgNames, []string{"nfg-a", "nfg-b"}) || entry.expiresAt.IsZero() {
+		t.Errorf("expected app:v1 to be restored with its NFGs and expiry, got %+v (found %v)", entry, found)
+	}
+	if entry, found := loaded.imageToNFGCache.get("app:v2"); !found || !entry.noRequirements || !entry.expiresAt.IsZero() {
+		t.Errorf("expected app:v2 to be restored without requirements or expiry, got %+v (found %v)", entry, found)
+	}
+	if _, found := loaded.imageToNFGCache.get("app:old"); found {
+		t.Errorf("expected the expired entry to be skipped on load")
+	}
+}
+
+func TestLoadCacheFileMissing(t *testing.T) {
+	plugin := &ImageCompatibilityPlugin{imageToNFGCache: newNFGCache()}
+	plugin.loadCacheFile(filepath.Join(t.TempDir(), "missing.json"))
+	if entries := plugin.cacheEntries(""); len(entries) != 0 {
+		t.Errorf("expected an empty cache, got %v", entries)
+	}
+}
@@ -0,0 +1,565 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: compatibility.proto
+
+package compatibilitypb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ValidateImageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Image         string                 `protobuf:"bytes,1,opt,name=image,proto3" json:"image,omitempty"`
+	Node          string                 `protobuf:"bytes,2,opt,name=node,proto3" json:"node,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ValidateImageRequest) Reset() {
+	*x = ValidateImageRequest{}
+	mi := &file_compatibility_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidateImageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateImageRequest) ProtoMessage() {}
+
+func (x *ValidateImageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_compatibility_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateImageRequest.ProtoReflect.Descriptor instead.
+func (*ValidateImageRequest) Descriptor() ([]byte, []int) {
+	return file_compatibility_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ValidateImageRequest) GetImage() string {
+	if x != nil {
+		return x.Image
+	}
+	return ""
+}
+
+func (x *ValidateImageRequest) GetNode() string {
+	if x != nil {
+		return x.Node
+	}
+	return ""
+}
+
+type ValidateImageResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Image         string                 `protobuf:"bytes,1,opt,name=image,proto3" json:"image,omitempty"`
+	Node          string                 `protobuf:"bytes,2,opt,name=node,proto3" json:"node,omitempty"`
+	Compatible    bool                   `protobuf:"varint,3,opt,name=compatible,proto3" json:"compatible,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ValidateImageResponse) Reset() {
+	*x = ValidateImageResponse{}
+	mi := &file_compatibility_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidateImageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateImageResponse) ProtoMessage() {}
+
+func (x *ValidateImageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_compatibility_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateImageResponse.ProtoReflect.Descriptor instead.
+func (*ValidateImageResponse) Descriptor() ([]byte, []int) {
+	return file_compatibility_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ValidateImageResponse) GetImage() string {
+	if x != nil {
+		return x.Image
+	}
+	return ""
+}
+
+func (x *ValidateImageResponse) GetNode() string {
+	if x != nil {
+		return x.Node
+	}
+	return ""
+}
+
+func (x *ValidateImageResponse) GetCompatible() bool {
+	if x != nil {
+		return x.Compatible
+	}
+	return false
+}
+
+type GetCompatibilityMatrixRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Images []string               `protobuf:"bytes,1,rep,name=images,proto3" json:"images,omitempty"`
+	// node_selector is a label selector, all nodes are evaluated when empty.
+	NodeSelector string `protobuf:"bytes,2,opt,name=node_selector,json=nodeSelector,proto3" json:"node_selector,omitempty"`
+	// by_architecture evaluates, for every node architecture, the variant of
+	// multi-platform images for that architecture against its nodes.
+	ByArchitecture bool `protobuf:"varint,3,opt,name=by_architecture,json=byArchitecture,proto3" json:"by_architecture,omitempty"`
+	// topology_key, e.g. topology.kubernetes.io/zone, also evaluates each
+	// domain of the key against the union of the features of its nodes.
+	TopologyKey   string `protobuf:"bytes,4,opt,name=topology_key,json=topologyKey,proto3" json:"topology_key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCompatibilityMatrixRequest) Reset() {
+	*x = GetCompatibilityMatrixRequest{}
+	mi := &file_compatibility_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCompatibilityMatrixRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCompatibilityMatrixRequest) ProtoMessage() {}
+
+func (x *GetCompatibilityMatrixRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_compatibility_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCompatibilityMatrixRequest.ProtoReflect.Descriptor instead.
+func (*GetCompatibilityMatrixRequest) Descriptor() ([]byte, []int) {
+	return file_compatibility_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetCompatibilityMatrixRequest) GetImages() []string {
+	if x != nil {
+		return x.Images
+	}
+	return nil
+}
+
+func (x *GetCompatibilityMatrixRequest) GetNodeSelector() string {
+	if x != nil {
+		return x.NodeSelector
+	}
+	return ""
+}
+
+func (x *GetCompatibilityMatrixRequest) GetByArchitecture() bool {
+	if x != nil {
+		return x.ByArchitecture
+	}
+	return false
+}
+
+func (x *GetCompatibilityMatrixRequest) GetTopologyKey() string {
+	if x != nil {
+		return x.TopologyKey
+	}
+	return ""
+}
+
+type GetCompatibilityMatrixResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rows          []*CompatibilityRow    `protobuf:"bytes,1,rep,name=rows,proto3" json:"rows,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCompatibilityMatrixResponse) Reset() {
+	*x = GetCompatibilityMatrixResponse{}
+	mi := &file_compatibility_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCompatibilityMatrixResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCompatibilityMatrixResponse) ProtoMessage() {}
+
+func (x *GetCompatibilityMatrixResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_compatibility_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCompatibilityMatrixResponse.ProtoReflect.Descriptor instead.
+func (*GetCompatibilityMatrixResponse) Descriptor() ([]byte, []int) {
+	return file_compatibility_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetCompatibilityMatrixResponse) GetRows() []*CompatibilityRow {
+	if x != nil {
+		return x.Rows
+	}
+	return nil
+}
+
+type CompatibilityRow struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Image             string                 `protobuf:"bytes,1,opt,name=image,proto3" json:"image,omitempty"`
+	CompatibleNodes   []string               `protobuf:"bytes,2,rep,name=compatible_nodes,json=compatibleNodes,proto3" json:"compatible_nodes,omitempty"`
+	IncompatibleNodes []string               `protobuf:"bytes,3,rep,name=incompatible_nodes,json=incompatibleNodes,proto3" json:"incompatible_nodes,omitempty"`
+	// architectures is set when by_architecture is requested.
+	Architectures []*ArchitectureCompatibility `protobuf:"bytes,4,rep,name=architectures,proto3" json:"architectures,omitempty"`
+	// domains is set when topology_key is requested.
+	Domains       []*DomainCompatibility `protobuf:"bytes,5,rep,name=domains,proto3" json:"domains,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CompatibilityRow) Reset() {
+	*x = CompatibilityRow{}
+	mi := &file_compatibility_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompatibilityRow) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompatibilityRow) ProtoMessage() {}
+
+func (x *CompatibilityRow) ProtoReflect() protoreflect.Message {
+	mi := &file_compatibility_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompatibilityRow.ProtoReflect.Descriptor instead.
+func (*CompatibilityRow) Descriptor() ([]byte, []int) {
+	return file_compatibility_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CompatibilityRow) GetImage() string {
+	if x != nil {
+		return x.Image
+	}
+	return ""
+}
+
+func (x *CompatibilityRow) GetCompatibleNodes() []string {
+	if x != nil {
+		return x.CompatibleNodes
+	}
+	return nil
+}
+
+func (x *CompatibilityRow) GetIncompatibleNodes() []string {
+	if x != nil {
+		return x.IncompatibleNodes
+	}
+	return nil
+}
+
+func (x *CompatibilityRow) GetArchitectures() []*ArchitectureCompatibility {
+	if x != nil {
+		return x.Architectures
+	}
+	return nil
+}
+
+func (x *CompatibilityRow) GetDomains() []*DomainCompatibility {
+	if x != nil {
+		return x.Domains
+	}
+	return nil
+}
+
+type ArchitectureCompatibility struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Architecture string                 `protobuf:"bytes,1,opt,name=architecture,proto3" json:"architecture,omitempty"`
+	// image is the evaluated variant, empty when the image has none for the
+	// architecture.
+	Image             string   `protobuf:"bytes,2,opt,name=image,proto3" json:"image,omitempty"`
+	CompatibleNodes   []string `protobuf:"bytes,3,rep,name=compatible_nodes,json=compatibleNodes,proto3" json:"compatible_nodes,omitempty"`
+	IncompatibleNodes []string `protobuf:"bytes,4,rep,name=incompatible_nodes,json=incompatibleNodes,proto3" json:"incompatible_nodes,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *ArchitectureCompatibility) Reset() {
+	*x = ArchitectureCompatibility{}
+	mi := &file_compatibility_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ArchitectureCompatibility) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ArchitectureCompatibility) ProtoMessage() {}
+
+func (x *ArchitectureCompatibility) ProtoReflect() protoreflect.Message {
+	mi := &file_compatibility_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ArchitectureCompatibility.ProtoReflect.Descriptor instead.
+func (*ArchitectureCompatibility) Descriptor() ([]byte, []int) {
+	return file_compatibility_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ArchitectureCompatibility) GetArchitecture() string {
+	if x != nil {
+		return x.Architecture
+	}
+	return ""
+}
+
+func (x *ArchitectureCompatibility) GetImage() string {
+	if x != nil {
+		return x.Image
+	}
+	return ""
+}
+
+func (x *ArchitectureCompatibility) GetCompatibleNodes() []string {
+	if x != nil {
+		return x.CompatibleNodes
+	}
+	return nil
+}
+
+func (x *ArchitectureCompatibility) GetIncompatibleNodes() []string {
+	if x != nil {
+		return x.IncompatibleNodes
+	}
+	return nil
+}
+
+type DomainCompatibility struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Domain string                 `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+	// compatible is set when the required compatibility sets of the image (or
+	// the minMatchRatio of them) are each satisfied by at least one node of
+	// the domain.
+	Compatible    bool     `protobuf:"varint,2,opt,name=compatible,proto3" json:"compatible,omitempty"`
+	Nodes         []string `protobuf:"bytes,3,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DomainCompatibility) Reset() {
+	*x = DomainCompatibility{}
+	mi := &file_compatibility_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DomainCompatibility) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DomainCompatibility) ProtoMessage() {}
+
+func (x *DomainCompatibility) ProtoReflect() protoreflect.Message {
+	mi := &file_compatibility_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DomainCompatibility.ProtoReflect.Descriptor instead.
+func (*DomainCompatibility) Descriptor() ([]byte, []int) {
+	return file_compatibility_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DomainCompatibility) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *DomainCompatibility) GetCompatible() bool {
+	if x != nil {
+		return x.Compatible
+	}
+	return false
+}
+
+func (x *DomainCompatibility) GetNodes() []string {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+var File_compatibility_proto protoreflect.FileDescriptor
+
+const file_compatibility_proto_rawDesc = "" +
+	"\n" +
+	"\x13compatibility.proto\x12\x1bimagecompatibility.v1alpha1\"@\n" +
+	"\x14ValidateImageRequest\x12\x14\n" +
+	"\x05image\x18\x01 \x01(\tR\x05image\x12\x12\n" +
+	"\x04node\x18\x02 \x01(\tR\x04node\"a\n" +
+	"\x15ValidateImageResponse\x12\x14\n" +
+	"\x05image\x18\x01 \x01(\tR\x05image\x12\x12\n" +
+	"\x04node\x18\x02 \x01(\tR\x04node\x12\x1e\n" +
+	"\n" +
+	"compatible\x18\x03 \x01(\bR\n" +
+	"compatible\"\xa8\x01\n" +
+	"\x1dGetCompatibilityMatrixRequest\x12\x16\n" +
+	"\x06images\x18\x01 \x03(\tR\x06images\x12#\n" +
+	"\rnode_selector\x18\x02 \x01(\tR\fnodeSelector\x12'\n" +
+	"\x0fby_architecture\x18\x03 \x01(\bR\x0ebyArchitecture\x12!\n" +
+	"\ftopology_key\x18\x04 \x01(\tR\vtopologyKey\"c\n" +
+	"\x1eGetCompatibilityMatrixResponse\x12A\n" +
+	"\x04rows\x18\x01 \x03(\v2-.imagecompatibility.v1alpha1.CompatibilityRowR\x04rows\"\xac\x02\n" +
+	"\x10CompatibilityRow\x12\x14\n" +
+	"\x05image\x18\x01 \x01(\tR\x05image\x12)\n" +
+	"\x10compatible_nodes\x18\x02 \x03(\tR\x0fcompatibleNodes\x12-\n" +
+	"\x12incompatible_nodes\x18\x03 \x03(\tR\x11incompatibleNodes\x12\\\n" +
+	"\rarchitectures\x18\x04 \x03(\v26.imagecompatibility.v1alpha1.ArchitectureCompatibilityR\rarchitectures\x12J\n" +
+	"\adomains\x18\x05 \x03(\v20.imagecompatibility.v1alpha1.DomainCompatibilityR\adomains\"\xaf\x01\n" +
+	"\x19ArchitectureCompatibility\x12\"\n" +
+	"\farchitecture\x18\x01 \x01(\tR\farchitecture\x12\x14\n" +
+	"\x05image\x18\x02 \x01(\tR\x05image\x12)\n" +
+	"\x10compatible_nodes\x18\x03 \x03(\tR\x0fcompatibleNodes\x12-\n" +
+	"\x12incompatible_nodes\x18\x04 \x03(\tR\x11incompatibleNodes\"c\n" +
+	"\x13DomainCompatibility\x12\x16\n" +
+	"\x06domain\x18\x01 \x01(\tR\x06domain\x12\x1e\n" +
+	"\n" +
+	"compatible\x18\x02 \x01(\bR\n" +
+	"compatible\x12\x14\n" +
+	"\x05nodes\x18\x03 \x03(\tR\x05nodes2\x9b\x02\n" +
+	"\rCompatibility\x12v\n" +
+	"\rValidateImage\x121.imagecompatibility.v1alpha1.ValidateImageRequest\x1a2.imagecompatibility.v1alpha1.ValidateImageResponse\x12\x91\x01\n" +
+	"\x16GetCompatibilityMatrix\x12:.imagecompatibility.v1alpha1.GetCompatibilityMatrixRequest\x1a;.imagecompatibility.v1alpha1.GetCompatibilityMatrixResponseBBZ@custom-scheduler/pkg/plugins/compatibilityPlugin/compatibilitypbb\x06proto3"
+
+var (
+	file_compatibility_proto_rawDescOnce sync.Once
+	file_compatibility_proto_rawDescData []byte
+)
+
+func file_compatibility_proto_rawDescGZIP() []byte {
+	file_compatibility_proto_rawDescOnce.Do(func() {
+		file_compatibility_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_compatibility_proto_rawDesc), len(file_compatibility_proto_rawDesc)))
+	})
+	return file_compatibility_proto_rawDescData
+}
+
+var file_compatibility_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_compatibility_proto_goTypes = []any{
+	(*ValidateImageRequest)(nil),           // 0: imagecompatibility.v1alpha1.ValidateImageRequest
+	(*ValidateImageResponse)(nil),          // 1: imagecompatibility.v1alpha1.ValidateImageResponse
+	(*GetCompatibilityMatrixRequest)(nil),  // 2: imagecompatibility.v1alpha1.GetCompatibilityMatrixRequest
+	(*GetCompatibilityMatrixResponse)(nil), // 3: imagecompatibility.v1alpha1.GetCompatibilityMatrixResponse
+	(*CompatibilityRow)(nil),               // 4: imagecompatibility.v1alpha1.CompatibilityRow
+	(*ArchitectureCompatibility)(nil),      // 5: imagecompatibility.v1alpha1.ArchitectureCompatibility
+	(*DomainCompatibility)(nil),            // 6: imagecompatibility.v1alpha1.DomainCompatibility
+}
+var file_compatibility_proto_depIdxs = []int32{
+	4, // 0: imagecompatibility.v1alpha1.GetCompatibilityMatrixResponse.rows:type_name -> imagecompatibility.v1alpha1.CompatibilityRow
+	5, // 1: imagecompatibility.v1alpha1.CompatibilityRow.architectures:type_name -> imagecompatibility.v1alpha1.ArchitectureCompatibility
+	6, // 2: imagecompatibility.v1alpha1.CompatibilityRow.domains:type_name -> imagecompatibility.v1alpha1.DomainCompatibility
+	0, // 3: imagecompatibility.v1alpha1.Compatibility.ValidateImage:input_type -> imagecompatibility.v1alpha1.ValidateImageRequest
+	2, // 4: imagecompatibility.v1alpha1.Compatibility.GetCompatibilityMatrix:input_type -> imagecompatibility.v1alpha1.GetCompatibilityMatrixRequest
+	1, // 5: imagecompatibility.v1alpha1.Compatibility.ValidateImage:output_type -> imagecompatibility.v1alpha1.ValidateImageResponse
+	3, // 6: imagecompatibility.v1alpha1.Compatibility.GetCompatibilityMatrix:output_type -> imagecompatibility.v1alpha1.GetCompatibilityMatrixResponse
+	5, // [5:7] is the sub-list for method output_type
+	3, // [3:5] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_compatibility_proto_init() }
+func file_compatibility_proto_init() {
+	if File_compatibility_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_compatibility_proto_rawDesc), len(file_compatibility_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_compatibility_proto_goTypes,
+		DependencyIndexes: file_compatibility_proto_depIdxs,
+		MessageInfos:      file_compatibility_proto_msgTypes,
+	}.Build()
+	File_compatibility_proto = out.File
+	file_compatibility_proto_goTypes = nil
+	file_compatibility_proto_depIdxs = nil
+}
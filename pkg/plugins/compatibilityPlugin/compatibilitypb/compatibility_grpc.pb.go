@@ -0,0 +1,173 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: compatibility.proto
+
+package compatibilitypb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Compatibility_ValidateImage_FullMethodName          = "/imagecompatibility.v1alpha1.Compatibility/ValidateImage"
+	Compatibility_GetCompatibilityMatrix_FullMethodName = "/imagecompatibility.v1alpha1.Compatibility/GetCompatibilityMatrix"
+)
+
+// CompatibilityClient is the client API for Compatibility service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Compatibility evaluates image compatibility artifacts against the
+// NodeFeature objects published by NFD, like the simulate subcommand.
+type CompatibilityClient interface {
+	// ValidateImage reports whether the image is compatible with the node,
+	// evaluating only that node.
+	ValidateImage(ctx context.Context, in *ValidateImageRequest, opts ...grpc.CallOption) (*ValidateImageResponse, error)
+	// GetCompatibilityMatrix reports the compatible and incompatible nodes of
+	// each image, among the nodes matching the label selector.
+	GetCompatibilityMatrix(ctx context.Context, in *GetCompatibilityMatrixRequest, opts ...grpc.CallOption) (*GetCompatibilityMatrixResponse, error)
+}
+
+type compatibilityClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCompatibilityClient(cc grpc.ClientConnInterface) CompatibilityClient {
+	return &compatibilityClient{cc}
+}
+
+func (c *compatibilityClient) ValidateImage(ctx context.Context, in *ValidateImageRequest, opts ...grpc.CallOption) (*ValidateImageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ValidateImageResponse)
+	err := c.cc.Invoke(ctx, Compatibility_ValidateImage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *compatibilityClient) GetCompatibilityMatrix(ctx context.Context, in *GetCompatibilityMatrixRequest, opts ...grpc.CallOption) (*GetCompatibilityMatrixResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetCompatibilityMatrixResponse)
+	err := c.cc.Invoke(ctx, Compatibility_GetCompatibilityMatrix_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CompatibilityServer is the server API for Compatibility service.
+// All implementations must embed UnimplementedCompatibilityServer
+// for forward compatibility.
+//
+// Compatibility evaluates image compatibility artifacts against the
+// NodeFeature objects published by NFD, like the simulate subcommand.
+type CompatibilityServer interface {
+	// ValidateImage reports whether the image is compatible with the node,
+	// evaluating only that node.
+	ValidateImage(context.Context, *ValidateImageRequest) (*ValidateImageResponse, error)
+	// GetCompatibilityMatrix reports the compatible and incompatible nodes of
+	// each image, among the nodes matching the label selector.
+	GetCompatibilityMatrix(context.Context, *GetCompatibilityMatrixRequest) (*GetCompatibilityMatrixResponse, error)
+	mustEmbedUnimplementedCompatibilityServer()
+}
+
+// UnimplementedCompatibilityServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCompatibilityServer struct{}
+
+func (UnimplementedCompatibilityServer) ValidateImage(context.Context, *ValidateImageRequest) (*ValidateImageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateImage not implemented")
+}
+func (UnimplementedCompatibilityServer) GetCompatibilityMatrix(context.Context, *GetCompatibilityMatrixRequest) (*GetCompatibilityMatrixResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCompatibilityMatrix not implemented")
+}
+func (UnimplementedCompatibilityServer) mustEmbedUnimplementedCompatibilityServer() {}
+func (UnimplementedCompatibilityServer) testEmbeddedByValue()                       {}
+
+// UnsafeCompatibilityServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CompatibilityServer will
+// result in compilation errors.
+type UnsafeCompatibilityServer interface {
+	mustEmbedUnimplementedCompatibilityServer()
+}
+
+func RegisterCompatibilityServer(s grpc.ServiceRegistrar, srv CompatibilityServer) {
+	// If the following call pancis, it indicates UnimplementedCompatibilityServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Compatibility_ServiceDesc, srv)
+}
+
+func _Compatibility_ValidateImage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateImageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CompatibilityServer).ValidateImage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Compatibility_ValidateImage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CompatibilityServer).ValidateImage(ctx, req.(*ValidateImageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Compatibility_GetCompatibilityMatrix_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCompatibilityMatrixRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CompatibilityServer).GetCompatibilityMatrix(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Compatibility_GetCompatibilityMatrix_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CompatibilityServer).GetCompatibilityMatrix(ctx, req.(*GetCompatibilityMatrixRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Compatibility_ServiceDesc is the grpc.ServiceDesc for Compatibility service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Compatibility_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "imagecompatibility.v1alpha1.Compatibility",
+	HandlerType: (*CompatibilityServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ValidateImage",
+			Handler:    _Compatibility_ValidateImage_Handler,
+		},
+		{
+			MethodName: "GetCompatibilityMatrix",
+			Handler:    _Compatibility_GetCompatibilityMatrix_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "compatibility.proto",
+}
@@ -0,0 +1,5 @@
+// Package compatibilitypb holds the gRPC API of the image compatibility
+// plugin, generated from compatibility.proto.
+package compatibilitypb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative compatibility.proto
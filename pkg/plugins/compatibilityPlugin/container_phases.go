@@ -0,0 +1,72 @@
+package compatibilityPlugin
+
+import (
+	"fmt"
+	"strings"
+
+	fwk "k8s.io/kube-scheduler/framework"
+)
+
+const (
+	// ContainerPhaseInit is the phase of the init container images.
+	ContainerPhaseInit = "init"
+	// ContainerPhaseMain is the phase of the regular container images and
+	// image volumes.
+	ContainerPhaseMain = "main"
+)
+
+// containerPhases lists the phases in the order they are reported.
+var containerPhases = []string{ContainerPhaseInit, ContainerPhaseMain}
+
+// podImages holds the deduplicated effective images of a pod by phase. An
+// image used by both init and regular containers is listed in both phases.
+type podImages map[string][]string
+
+// all returns the images of all phases, deduplicated, init images first.
+func (p podImages) all() []string {
+	var images []string
+	seen := make(map[string]struct{})
+	for _, phase := range containerPhases {
+		for _, image := range p[phase] {
+			if _, ok := seen[image]; ok {
+				continue
+			}
+			seen[image] = struct{}{}
+			images = append(images, image)
+		}
+	}
+	return images
+}
+
+// phaseCompatibility combines the compatible nodes of each phase. Phases
+// missing from byPhase have no requirements. It returns the nodes compatible
+// in every phase and the phases in which each of the other nodes is not.
+func phaseCompatibility(byPhase map[string]map[string]struct{}, nodes []fwk.NodeInfo) (map[string]struct{}, map[string][]string) {
+	compatible := make(map[string]struct{}, len(nodes))
+	incompatible := make(map[string][]string)
+	for _, nodeInfo := range nodes {
+		node := nodeInfo.Node()
+		if node == nil {
+			continue
+		}
+		for _, phase := range containerPhases {
+			set, ok := byPhase[phase]
+			if !ok {
+				continue
+			}
+			if _, ok := set[node.Name]; !ok {
+				incompatible[node.Name] = append(incompatible[node.Name], phase)
+			}
+		}
+		if len(incompatible[node.Name]) == 0 {
+			compatible[node.Name] = struct{}{}
+		}
+	}
+	return compatible, incompatible
+}
+
+// incompatiblePhasesReason returns the Filter reason of a node incompatible
+// with the images of the given phases.
+func incompatiblePhasesReason(nodeName string, phases []string) string {
+	return fmt.Sprintf("node %s is not compatible with the %s container images", nodeName, strings.Join(phases, " and "))
+}
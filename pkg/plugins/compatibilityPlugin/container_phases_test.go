@@ -0,0 +1,147 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fwk "k8s.io/kube-scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func TestResolvePodImagesByPhase(t *testing.T) {
+	plugin := &ImageCompatibilityPlugin{imageResolver: newPrefixImageResolver(nil)}
+	pod := &v1.Pod{Spec: v1.PodSpec{
+		InitContainers: []v1.Container{{Image: "model-download:v1"}, {Image: "app:v1"}},
+		Containers:     []v1.Container{{Image: "app:v1"}, {Image: "sidecar:v1"}, {Image: "app:v1"}},
+	}}
+
+	images, err := plugin.resolvePodImages(context.Background(), pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := podImages{
+		ContainerPhaseInit: {"model-download:v1", "app:v1"},
+		ContainerPhaseMain: {"app:v1", "sidecar:v1"},
+	}
+	if !reflect.DeepEqual(images, want) {
+		t.Errorf("expected %v, got %v", want, images)
+	}
+	if got := images.all(); !reflect.DeepEqual(got, []string{"model-download:v1", "app:v1", "sidecar:v1"}) {
+		t.Errorf("unexpected deduplicated images %v", got)
+	}
+}
+
+func TestPhaseCompatibility(t *testing.T) {
+	var nodes []fwk.NodeInfo
+	for _, name := range []string{"gpu", "cpu", "none"} {
+		ni := framework.NewNodeInfo()
+		ni.SetNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		nodes = append(nodes, ni)
+	}
+	byPhase := map[string]map[string]struct{}{
+		ContainerPhaseInit: {"gpu": {}},
+		ContainerPhaseMain: {"gpu": {}, "cpu": {}},
+	}
+
+	compatible, incompatible := phaseCompatibility(byPhase, nodes)
+	if !reflect.DeepEqual(compatible, map[string]struct{}{"gpu": {}}) {
+		t.Errorf("expected only gpu to be compatible, got %v", compatible)
+	}
+	want := map[string][]string{
+		"cpu":  {ContainerPhaseInit},
+		"none": {ContainerPhaseInit, ContainerPhaseMain},
+	}
+	if !reflect.DeepEqual(incompatible, want) {
+		t.Errorf("expected incompatible phases %v, got %v", want, incompatible)
+	}
+
+	plugin := &ImageCompatibilityPlugin{}
+	cycleState := framework.NewCycleState()
+	cycleState.Write(PluginName, &CompatibilityState{CompatibleNodes: compatible, IncompatiblePhases: incompatible})
+	status := plugin.Filter(context.Background(), cycleState, &v1.Pod{}, nodes[1])
+	if status.Code() != fwk.Unschedulable || status.Message() != "node cpu is not compatible with the init container images" {
+		t.Errorf("unexpected status for cpu: %v", status)
+	}
+}
+
+func TestResolvePodImagesWithImageVolumes(t *testing.T) {
+	pod := &v1.Pod{Spec: v1.PodSpec{
+		Containers: []v1.Container{{Image: "app:v1"}},
+		Volumes: []v1.Volume{
+			{Name: "model", VolumeSource: v1.VolumeSource{Image: &v1.ImageVolumeSource{Reference: "model:v1"}}},
+			{Name: "same", VolumeSource: v1.VolumeSource{Image: &v1.ImageVolumeSource{Reference: "app:v1"}}},
+			{Name: "config", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}},
+		},
+	}}
+
+	plugin := &ImageCompatibilityPlugin{imageResolver: newPrefixImageResolver(nil)}
+	images, err := plugin.resolvePodImages(context.Background(), pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := images[ContainerPhaseMain]; !reflect.DeepEqual(got, []string{"app:v1", "model:v1"}) {
+		t.Errorf("expected container and volume images, got %v", got)
+	}
+
+	disabled := false
+	plugin.args.CheckImageVolumes = &disabled
+	images, err = plugin.resolvePodImages(context.Background(), pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := images[ContainerPhaseMain]; !reflect.DeepEqual(got, []string{"app:v1"}) {
+		t.Errorf("expected only container images, got %v", got)
+	}
+}
+
+func TestResolvePodImagesSandboxImage(t *testing.T) {
+	kata := "kata"
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app", Image: "app:v1"}}}}
+	plugin := &ImageCompatibilityPlugin{
+		imageResolver: newPrefixImageResolver(nil),
+		args: ImageCompatibilityPluginArgs{
+			CheckSandboxImage:         true,
+			SandboxImage:              "pause:3.10",
+			RuntimeClassSandboxImages: map[string]string{kata: "kata-pause:1.0"},
+		},
+	}
+
+	images, err := plugin.resolvePodImages(context.Background(), pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := images[ContainerPhaseMain]; !reflect.DeepEqual(got, []string{"app:v1", "pause:3.10"}) {
+		t.Errorf("expected container and default sandbox images, got %v", got)
+	}
+
+	pod.Spec.RuntimeClassName = &kata
+	images, err = plugin.resolvePodImages(context.Background(), pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := images[ContainerPhaseMain]; !reflect.DeepEqual(got, []string{"app:v1", "kata-pause:1.0"}) {
+		t.Errorf("expected container and runtime class sandbox images, got %v", got)
+	}
+}
+
+func TestResolvePodImagesSkipsLocalImages(t *testing.T) {
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{
+		{Name: "app", Image: "registry.example.com/app:v1"},
+		{Name: "imported", Image: "tools:v1"},
+	}}}
+	plugin := &ImageCompatibilityPlugin{
+		imageResolver: newPrefixImageResolver(nil),
+		args:          ImageCompatibilityPluginArgs{SkipLocalImages: true},
+	}
+
+	images, err := plugin.resolvePodImages(context.Background(), pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := images[ContainerPhaseMain]; !reflect.DeepEqual(got, []string{"registry.example.com/app:v1"}) {
+		t.Errorf("expected the local image to be skipped, got %v", got)
+	}
+}
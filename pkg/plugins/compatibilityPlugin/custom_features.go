@@ -0,0 +1,162 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	k8sclient "k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	fwk "k8s.io/kube-scheduler/framework"
+	nfdclientset "sigs.k8s.io/node-feature-discovery/api/generated/clientset/versioned"
+	nfdinformers "sigs.k8s.io/node-feature-discovery/api/generated/informers/externalversions"
+	nfdlisters "sigs.k8s.io/node-feature-discovery/api/generated/listers/nfd/v1alpha1"
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
+)
+
+// customFeatureSource merges manually tracked node features from a ConfigMap
+// into the features discovered by NFD, watching both with informers.
+type customFeatureSource struct {
+	configMapNamespace string
+	configMapName      string
+	keyLabel           string
+	configMaps         corelisters.ConfigMapLister
+	nodeFeatures       nfdlisters.NodeFeatureLister
+	synced             []cache.InformerSynced
+
+	mu              sync.Mutex
+	resourceVersion string                           // ConfigMap revision of custom
+	custom          map[string]*nfdv1alpha1.Features // Parsed custom features by key
+}
+
+// newCustomFeatureSource creates the informers watching the custom features
+// ConfigMap and the NodeFeature objects and starts them until ctx is done.
+func newCustomFeatureSource(ctx context.Context, kubeClient k8sclient.Interface, nfdClient nfdclientset.Interface, args ImageCompatibilityPluginArgs) (*customFeatureSource, error) {
+	nn, err := parseNamespacedName(args.CustomFeaturesConfigMap)
+	if err != nil {
+		return nil, err
+	}
+
+	cmFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient, 0,
+		informers.WithNamespace(nn.Namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector(metav1.ObjectNameField, nn.Name).String()
+		}))
+	cmInformer := cmFactory.Core().V1().ConfigMaps()
+
+	nfdFactory := nfdinformers.NewSharedInformerFactory(nfdClient, 0)
+	nfInformer := nfdFactory.Nfd().V1alpha1().NodeFeatures()
+
+	s := &customFeatureSource{
+		configMapNamespace: nn.Namespace,
+		configMapName:      nn.Name,
+		keyLabel:           args.CustomFeaturesKeyLabel,
+		configMaps:         cmInformer.Lister(),
+		nodeFeatures:       nfInformer.Lister(),
+		synced:             []cache.InformerSynced{cmInformer.Informer().HasSynced, nfInformer.Informer().HasSynced},
+	}
+	cmFactory.Start(ctx.Done())
+	nfdFactory.Start(ctx.Done())
+	return s, nil
+}
+
+// customFeatures returns the parsed custom features, reparsing the ConfigMap
+// only when it changed.
+func (s *customFeatureSource) customFeatures() (map[string]*nfdv1alpha1.Features, error) {
+	cm, err := s.configMaps.ConfigMaps(s.configMapNamespace).Get(s.configMapName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get custom features ConfigMap %s/%s: %w", s.configMapNamespace, s.configMapName, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.custom != nil && s.resourceVersion == cm.ResourceVersion {
+		return s.custom, nil
+	}
+	// The data has the same format as the instance type features
+	custom, err := parseInstanceTypeFeatures(cm.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse custom features ConfigMap %s/%s: %w", s.configMapNamespace, s.configMapName, err)
+	}
+	s.resourceVersion, s.custom = cm.ResourceVersion, custom
+	return custom, nil
+}
+
+// featuresByNode returns the NFD features of each node merged with the
+// custom features of the node. Custom features take precedence.
+func (s *customFeatureSource) featuresByNode(nodes []fwk.NodeInfo) (map[string]*nfdv1alpha1.Features, error) {
+	for _, synced := range s.synced {
+		if !synced() {
+			return nil, fmt.Errorf("custom feature informers not synced yet")
+		}
+	}
+
+	custom, err := s.customFeatures()
+	if err != nil {
+		return nil, err
+	}
+
+	nodeFeatures, err := s.nodeFeatures.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list NodeFeatures: %w", err)
+	}
+	objs := make([]nfdv1alpha1.NodeFeature, 0, len(nodeFeatures))
+	for _, nf := range nodeFeatures {
+		objs = append(objs, *nf)
+	}
+	discovered := mergeNodeFeatures(objs)
+
+	featuresByNode := make(map[string]*nfdv1alpha1.Features, len(nodes))
+	for _, nodeInfo := range nodes {
+		node := nodeInfo.Node()
+		if node == nil {
+			continue
+		}
+		features, ok := discovered[node.Name]
+		if !ok {
+			features = nfdv1alpha1.NewFeatures()
+		}
+		key := node.Name
+		if s.keyLabel != "" {
+			key = node.Labels[s.keyLabel]
+		}
+		if extra, ok := custom[key]; ok {
+			features = features.DeepCopy()
+			extra.MergeInto(features)
+		}
+		featuresByNode[node.Name] = features
+	}
+	return featuresByNode, nil
+}
+
+// compatibleNodesByCustomFeatures evaluates the images in-process against the
+// NFD features merged with the custom features of the nodes, with the
+// compatibility sets of the images cached as for instance types. It returns
+// ok=false when the mode is disabled or not ready, in which case the
+// NodeFeatureGroups have to be used instead.
+func (f *ImageCompatibilityPlugin) compatibleNodesByCustomFeatures(ctx context.Context, images podImages, runtimeClass string, nodes []fwk.NodeInfo) (byPhase map[string]map[string]struct{}, preferred []preferredSet, ok bool, err error) {
+	if f.customFeatures == nil {
+		return nil, nil, false, nil
+	}
+
+	featuresByNode, err := f.customFeatures.featuresByNode(nodes)
+	if err != nil {
+		log.Printf("Custom feature evaluation unavailable, falling back to NodeFeatureGroups: %v", err)
+		return nil, nil, false, nil
+	}
+
+	start := time.Now()
+	byPhase, preferred, err = f.evaluateInProcess(ctx, images, runtimeClass, featuresByNode)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	log.Printf("Evaluated images %v against custom features of %d nodes in %v", images.all(), len(featuresByNode), time.Since(start))
+	return byPhase, preferred, true, nil
+}
@@ -0,0 +1,78 @@
+package compatibilityPlugin
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	fwk "k8s.io/kube-scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	nfdlisters "sigs.k8s.io/node-feature-discovery/api/generated/listers/nfd/v1alpha1"
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
+)
+
+func TestCustomFeaturesByNode(t *testing.T) {
+	cmIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	_ = cmIndexer.Add(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "custom-features", ResourceVersion: "1"},
+		Data: map[string]string{
+			"rack-a": `
+attributes:
+  cpu.model:
+    elements:
+      vendor_id: AMD
+flags:
+  custom.fpga:
+    elements:
+      xilinx: {}
+`,
+		},
+	})
+
+	nf := &nfdv1alpha1.NodeFeature{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "node-feature-discovery",
+			Name:      "node1",
+			Labels:    map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: "node1"},
+		},
+		Spec: nfdv1alpha1.NodeFeatureSpec{Features: *nfdv1alpha1.NewFeatures()},
+	}
+	nf.Spec.Features.Attributes["cpu.model"] = nfdv1alpha1.AttributeFeatureSet{Elements: map[string]string{"vendor_id": "Intel", "family": "6"}}
+	nfIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	_ = nfIndexer.Add(nf)
+
+	s := &customFeatureSource{
+		configMapNamespace: "kube-system",
+		configMapName:      "custom-features",
+		keyLabel:           "topology.example.com/rack",
+		configMaps:         corelisters.NewConfigMapLister(cmIndexer),
+		nodeFeatures:       nfdlisters.NewNodeFeatureLister(nfIndexer),
+	}
+
+	var nodes []fwk.NodeInfo
+	for name, rack := range map[string]string{"node1": "rack-a", "node2": "rack-b"} {
+		ni := framework.NewNodeInfo()
+		ni.SetNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"topology.example.com/rack": rack}}})
+		nodes = append(nodes, ni)
+	}
+
+	features, err := s.featuresByNode(nodes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	node1 := features["node1"]
+	if got := node1.Attributes["cpu.model"].Elements["vendor_id"]; got != "AMD" {
+		t.Errorf("expected custom vendor AMD to take precedence, got %q", got)
+	}
+	if _, ok := node1.Flags["custom.fpga"].Elements["xilinx"]; !ok {
+		t.Errorf("expected node1 to have the custom fpga flag")
+	}
+	if got := nf.Spec.Features.Attributes["cpu.model"].Elements["vendor_id"]; got != "Intel" {
+		t.Errorf("expected NodeFeature object to be unmodified, got vendor %q", got)
+	}
+	if node2, ok := features["node2"]; !ok || len(node2.Flags) != 0 {
+		t.Errorf("expected node2 with empty features, got %+v", node2)
+	}
+}
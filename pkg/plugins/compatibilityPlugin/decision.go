@@ -0,0 +1,73 @@
+package compatibilityPlugin
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
+)
+
+// CompatibilitySetStatus is whether a node satisfies a required
+// compatibility set of the pod images.
+type CompatibilitySetStatus struct {
+	// Tag is the tag of the compatibility set, if any
+	Tag     string
+	Matched bool
+}
+
+// DecisionFunc decides whether a node is compatible with the pod images of a
+// container phase, given whether it satisfies each of their required
+// compatibility sets. Weighted sets only score nodes and are not passed.
+type DecisionFunc func(node string, sets []CompatibilitySetStatus) bool
+
+// MatchRatioDecision returns a DecisionFunc accepting the nodes satisfying at
+// least ratio of the required compatibility sets. MatchRatioDecision(1), all
+// sets, is the default unless MinMatchRatio is set.
+func MatchRatioDecision(ratio float64) DecisionFunc {
+	return func(_ string, sets []CompatibilitySetStatus) bool {
+		matched := 0
+		for _, set := range sets {
+			if set.Matched {
+				matched++
+			}
+		}
+		return meetsMatchRatio(matched, len(sets), ratio)
+	}
+}
+
+// NewWithDecisionFunc returns a plugin factory that decides whether nodes are
+// compatible with decide instead of MinMatchRatio.
+func NewWithDecisionFunc(decide DecisionFunc) frameworkruntime.PluginFactory {
+	return func(ctx context.Context, configuration runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+		p, err := New(ctx, configuration, handle)
+		if err != nil {
+			return nil, err
+		}
+		p.(*ImageCompatibilityPlugin).decide = decide
+		return p, nil
+	}
+}
+
+// decision returns the DecisionFunc of the plugin, MatchRatioDecision with
+// the configured MinMatchRatio by default.
+func (f *ImageCompatibilityPlugin) decision() DecisionFunc {
+	if f.decide != nil {
+		return f.decide
+	}
+	return MatchRatioDecision(minMatchRatio(f.args))
+}
+
+// requiredSetStatuses returns whether the features of the node satisfy each
+// of the required compatibility sets, for a DecisionFunc.
+func requiredSetStatuses(nodeName string, features *nfdv1alpha1.Features, required []nfdv1alpha1.NodeFeatureGroup) []CompatibilitySetStatus {
+	sets := make([]CompatibilitySetStatus, len(required))
+	for i := range required {
+		sets[i] = CompatibilitySetStatus{
+			Tag:     required[i].Annotations[NFGTagAnnotation],
+			Matched: matchesAllGroups(nodeName, features, required[i:i+1]),
+		}
+	}
+	return sets
+}
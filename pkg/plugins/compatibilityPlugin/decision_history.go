@@ -0,0 +1,146 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	fwk "k8s.io/kube-scheduler/framework"
+	nfdclientset "sigs.k8s.io/node-feature-discovery/api/generated/clientset/versioned"
+	nfdinformers "sigs.k8s.io/node-feature-discovery/api/generated/informers/externalversions"
+	nfdlisters "sigs.k8s.io/node-feature-discovery/api/generated/listers/nfd/v1alpha1"
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
+)
+
+// DecisionRecord is a compatibility result with the feature set of each
+// evaluated node it was computed from, so that the decision can be audited
+// after the node features change.
+type DecisionRecord struct {
+	DecidedAt time.Time `json:"decidedAt"`
+	*CompatibilityResult
+	// Features are the NFD features of the nodes for the nodeFeatureGroups
+	// source, merged with the custom features for customFeatures, or the
+	// instance type features for instanceType
+	Features map[string]*nfdv1alpha1.Features `json:"features,omitempty"`
+}
+
+// decisionHistory keeps the most recent decisions, evicting the oldest
+// beyond its capacity. A nil history records nothing.
+type decisionHistory struct {
+	mu       sync.Mutex
+	capacity int
+	records  []DecisionRecord // Ring buffer, next is the slot of the next record
+	next     int
+}
+
+// newDecisionHistory creates a history of at most capacity decisions, or nil
+// if capacity is not positive.
+func newDecisionHistory(capacity int) *decisionHistory {
+	if capacity <= 0 {
+		return nil
+	}
+	return &decisionHistory{capacity: capacity}
+}
+
+// record stores the decision.
+func (h *decisionHistory) record(rec DecisionRecord) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.records) < h.capacity {
+		h.records = append(h.records, rec)
+	} else {
+		h.records[h.next] = rec
+	}
+	h.next = (h.next + 1) % h.capacity
+}
+
+// list returns the decisions, most recent first, or only those of pod
+// ("namespace/name") if it is not empty.
+func (h *decisionHistory) list(pod string) []DecisionRecord {
+	records := []DecisionRecord{}
+	if h == nil {
+		return records
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := range h.records {
+		// Walk back from the most recent record
+		rec := h.records[(h.next-1-i+2*len(h.records))%len(h.records)]
+		if pod == "" || rec.Pod == pod {
+			records = append(records, rec)
+		}
+	}
+	return records
+}
+
+// newNodeFeatureLister starts an informer watching the NodeFeature objects
+// until ctx is done.
+func newNodeFeatureLister(ctx context.Context, nfdClient nfdclientset.Interface) (nfdlisters.NodeFeatureLister, cache.InformerSynced) {
+	factory := nfdinformers.NewSharedInformerFactory(nfdClient, 0)
+	informer := factory.Nfd().V1alpha1().NodeFeatures()
+	lister, synced := informer.Lister(), informer.Informer().HasSynced
+	factory.Start(ctx.Done())
+	return lister, synced
+}
+
+// recordDecision records the result with the features of the nodes it was
+// computed from, when RetainLastDecisions is set. Failing to snapshot the
+// features is reported in the record rather than failing the decision.
+func (f *ImageCompatibilityPlugin) recordDecision(ctx context.Context, result *CompatibilityResult, nodes []fwk.NodeInfo) {
+	if f.decisions == nil {
+		return
+	}
+	features, err := f.decisionFeatures(ctx, result.Source, nodes)
+	if err != nil {
+		log.Printf("Failed to snapshot the node features of the decision for pod %s: %v", result.Pod, err)
+	}
+	f.decisions.record(DecisionRecord{DecidedAt: time.Now().UTC(), CompatibilityResult: result, Features: features})
+}
+
+// decisionFeatures returns the feature set of each node the source evaluated.
+func (f *ImageCompatibilityPlugin) decisionFeatures(ctx context.Context, source string, nodes []fwk.NodeInfo) (map[string]*nfdv1alpha1.Features, error) {
+	switch source {
+	case CompatibilitySourceInstanceType:
+		instanceTypes, err := f.getInstanceTypeFeatures(ctx)
+		if err != nil {
+			return nil, err
+		}
+		features := make(map[string]*nfdv1alpha1.Features, len(nodes))
+		for _, nodeInfo := range nodes {
+			if node := nodeInfo.Node(); node != nil {
+				features[node.Name] = instanceTypes[node.Labels[v1.LabelInstanceTypeStable]]
+			}
+		}
+		return features, nil
+	case CompatibilitySourceCustomFeatures:
+		return f.customFeatures.featuresByNode(nodes)
+	}
+
+	if f.nodeFeatures == nil || !f.nodeFeaturesSynced() {
+		return nil, fmt.Errorf("NodeFeature informer not synced yet")
+	}
+	nodeFeatures, err := f.nodeFeatures.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list NodeFeatures: %w", err)
+	}
+	objs := make([]nfdv1alpha1.NodeFeature, 0, len(nodeFeatures))
+	for _, nf := range nodeFeatures {
+		objs = append(objs, *nf)
+	}
+	discovered := mergeNodeFeatures(objs)
+	features := make(map[string]*nfdv1alpha1.Features, len(nodes))
+	for _, nodeInfo := range nodes {
+		if node := nodeInfo.Node(); node != nil {
+			features[node.Name] = discovered[node.Name]
+		}
+	}
+	return features, nil
+}
@@ -0,0 +1,71 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	fwk "k8s.io/kube-scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	nfdlisters "sigs.k8s.io/node-feature-discovery/api/generated/listers/nfd/v1alpha1"
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
+)
+
+func TestDecisionHistory(t *testing.T) {
+	h := newDecisionHistory(3)
+	for _, pod := range []string{"ns/a", "ns/b", "ns/a", "ns/c"} {
+		h.record(DecisionRecord{CompatibilityResult: &CompatibilityResult{Pod: pod}})
+	}
+
+	pods := func(records []DecisionRecord) []string {
+		var names []string
+		for _, rec := range records {
+			names = append(names, rec.Pod)
+		}
+		return names
+	}
+	if got, want := pods(h.list("")), []string{"ns/c", "ns/a", "ns/b"}; !slices.Equal(got, want) {
+		t.Errorf("expected the last 3 decisions most recent first %v, got %v", want, got)
+	}
+	if got := h.list("ns/a"); len(got) != 1 {
+		t.Errorf("expected the retained decision of ns/a only, got %v", pods(got))
+	}
+	if got := (*decisionHistory)(nil).list(""); len(got) != 0 {
+		t.Errorf("expected a disabled history to list nothing, got %v", got)
+	}
+}
+
+func TestRecordDecisionSnapshotsNodeFeatures(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, nf := range []nfdv1alpha1.NodeFeature{vendorNodeFeature("intel-node", "Intel"), vendorNodeFeature("amd-node", "AMD")} {
+		nf.Namespace = "nfd"
+		if err := indexer.Add(&nf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	plugin := &ImageCompatibilityPlugin{
+		decisions:          newDecisionHistory(10),
+		nodeFeatures:       nfdlisters.NewNodeFeatureLister(indexer),
+		nodeFeaturesSynced: func() bool { return true },
+	}
+
+	nodeInfo := framework.NewNodeInfo()
+	nodeInfo.SetNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "intel-node"}})
+	result := &CompatibilityResult{Pod: "ns/app", Source: CompatibilitySourceNodeFeatureGroups, CompatibleNodes: []string{"intel-node"}}
+	plugin.recordDecision(context.Background(), result, []fwk.NodeInfo{nodeInfo})
+
+	records := plugin.decisions.list("ns/app")
+	if len(records) != 1 {
+		t.Fatalf("expected one recorded decision, got %d", len(records))
+	}
+	features := records[0].Features
+	if len(features) != 1 || features["intel-node"] == nil {
+		t.Fatalf("expected the features of the evaluated node only, got %v", features)
+	}
+	if vendor := features["intel-node"].Attributes["cpu.model"].Elements["vendor_id"]; vendor != "Intel" {
+		t.Errorf("expected the snapshot to hold the node's CPU vendor, got %q", vendor)
+	}
+}
@@ -0,0 +1,57 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	nfdfake "sigs.k8s.io/node-feature-discovery/api/generated/clientset/versioned/fake"
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
+)
+
+func TestComputeCompatibleNodesDecision(t *testing.T) {
+	nfg := func(name, tag string, nodes ...string) *nfdv1alpha1.NodeFeatureGroup {
+		obj := &nfdv1alpha1.NodeFeatureGroup{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "nfd"}}
+		if tag != "" {
+			obj.Annotations = map[string]string{NFGTagAnnotation: tag}
+		}
+		for _, n := range nodes {
+			obj.Status.Nodes = append(obj.Status.Nodes, nfdv1alpha1.FeatureGroupNode{Name: n})
+		}
+		return obj
+	}
+	nfdCli := nfdfake.NewSimpleClientset(
+		nfg("kernel", "kernel", "n1", "n2"),
+		nfg("gpu", "gpu", "n1", "n3"),
+	)
+
+	// Only the gpu set is mandatory
+	gpuOnly := func(_ string, sets []CompatibilitySetStatus) bool {
+		for _, set := range sets {
+			if set.Tag == "gpu" && !set.Matched {
+				return false
+			}
+		}
+		return true
+	}
+
+	tests := []struct {
+		name   string
+		decide DecisionFunc
+		want   map[string]struct{}
+	}{
+		{name: "default all sets", want: map[string]struct{}{"n1": {}}},
+		{name: "match ratio", decide: MatchRatioDecision(0.5), want: map[string]struct{}{"n1": {}, "n2": {}, "n3": {}}},
+		{name: "custom decision", decide: gpuOnly, want: map[string]struct{}{"n1": {}, "n3": {}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &ImageCompatibilityPlugin{nfdClient: nfdCli, decide: tt.decide}
+			got := plugin.computeCompatibleNodes(context.Background(), "nfd", []string{"kernel", "gpu"})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("computeCompatibleNodes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package compatibilityPlugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"text/template"
+
+	oras "oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/retry"
+	compatv1alpha1 "sigs.k8s.io/node-feature-discovery/api/image-compatibility/v1alpha1"
+	artifactcli "sigs.k8s.io/node-feature-discovery/pkg/client-nfd/compat/artifact-client"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// artifactReferenceData holds the image reference fields available to
+// ArtifactReferenceTemplate.
+type artifactReferenceData struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// parseArtifactReferenceTemplate parses an ArtifactReferenceTemplate.
+func parseArtifactReferenceTemplate(text string) (*template.Template, error) {
+	return template.New("artifactReference").Option("missingkey=error").Parse(text)
+}
+
+// deriveArtifactReference renders the template for the image reference and
+// parses the result as the reference of the compatibility artifact.
+func deriveArtifactReference(text string, ref registry.Reference) (registry.Reference, error) {
+	tmpl, err := parseArtifactReferenceTemplate(text)
+	if err != nil {
+		return registry.Reference{}, err
+	}
+
+	data := artifactReferenceData{Registry: ref.Registry, Repository: ref.Repository}
+	if err := ref.ValidateReferenceAsDigest(); err == nil {
+		data.Digest = ref.Reference
+	} else {
+		data.Tag = ref.Reference
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return registry.Reference{}, err
+	}
+	derived, err := registry.ParseReference(buf.String())
+	if err != nil {
+		return registry.Reference{}, fmt.Errorf("invalid derived artifact reference %q: %w", buf.String(), err)
+	}
+	return derived, nil
+}
+
+// referenceArtifactClient fetches a compatibility artifact published at its
+// own reference, rather than attached to the image as a referrer.
+type referenceArtifactClient struct {
+	ref       registry.Reference
+	client    remote.Client
+	plainHTTP bool
+}
+
+// newReferenceArtifactClient creates a referenceArtifactClient authenticated
+// with the credential, if any.
+func newReferenceArtifactClient(ref registry.Reference, cred *registryCredential, plainHTTP bool) *referenceArtifactClient {
+	return &referenceArtifactClient{ref: ref, client: newRegistryClient(ref.Registry, cred), plainHTTP: plainHTTP}
+}
+
+// newRegistryClient returns a registry client authenticated with the
+// credential, if any. A docker config identity token is an OAuth2 refresh
+// token, exchanged for access tokens by the client.
+func newRegistryClient(registryHost string, cred *registryCredential) remote.Client {
+	if cred == nil {
+		return auth.DefaultClient
+	}
+	credential := auth.Credential{Username: cred.Username, Password: cred.Password}
+	if cred.IdentityToken != "" {
+		credential = auth.Credential{Username: cred.Username, RefreshToken: cred.IdentityToken}
+	}
+	return &auth.Client{
+		Client:     retry.DefaultClient,
+		Cache:      auth.NewCache(),
+		Credential: auth.StaticCredential(registryHost, credential),
+	}
+}
+
+// FetchCompatibilitySpec fetches the artifact manifest and returns the spec
+// stored in its first layer.
+func (c *referenceArtifactClient) FetchCompatibilitySpec(ctx context.Context) (*compatv1alpha1.Spec, error) {
+	repo, err := remote.NewRepository(c.ref.String())
+	if err != nil {
+		return nil, err
+	}
+	repo.Client = c.client
+	repo.PlainHTTP = c.plainHTTP
+
+	_, content, err := oras.FetchBytes(ctx, repo, c.ref.Reference, oras.DefaultFetchBytesOptions)
+	if err != nil {
+		return nil, err
+	}
+	var manifest struct {
+		Layers []struct {
+			Digest string `json:"digest"`
+		} `json:"layers"`
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, err
+	}
+	if len(manifest.Layers) < 1 {
+		return nil, fmt.Errorf("compatibility layer not found in %s", c.ref)
+	}
+
+	_, specRaw, err := oras.FetchBytes(ctx, repo.Blobs(), manifest.Layers[0].Digest, oras.DefaultFetchBytesOptions)
+	if err != nil {
+		return nil, err
+	}
+	spec := &compatv1alpha1.Spec{}
+	if err := sigsyaml.Unmarshal(specRaw, spec); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// fallbackArtifactClient fetches the compatibility artifact from a derived
+// reference, falling back to the image referrers when it does not exist.
+type fallbackArtifactClient struct {
+	derived  artifactcli.ArtifactClient
+	fallback artifactcli.ArtifactClient
+	image    string
+}
+
+func (c *fallbackArtifactClient) FetchCompatibilitySpec(ctx context.Context) (*compatv1alpha1.Spec, error) {
+	spec, err := c.derived.FetchCompatibilitySpec(ctx)
+	if err != nil && isImageNotFoundError(err) {
+		log.Printf("Derived compatibility artifact of image %s not found, using the image referrers: %v", c.image, err)
+		return c.fallback.FetchCompatibilitySpec(ctx)
+	}
+	return spec, err
+}
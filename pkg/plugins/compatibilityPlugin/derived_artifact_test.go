@@ -0,0 +1,83 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/registry"
+	compatv1alpha1 "sigs.k8s.io/node-feature-discovery/api/image-compatibility/v1alpha1"
+)
+
+func TestDeriveArtifactReference(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		image    string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "tag suffix",
+			template: "{{.Registry}}/{{.Repository}}:{{.Tag}}-compat",
+			image:    "registry.example.com/team/app:v1",
+			want:     "registry.example.com/team/app:v1-compat",
+		},
+		{
+			name:     "separate repository",
+			template: "{{.Registry}}/compat/{{.Repository}}:{{.Tag}}",
+			image:    "registry.example.com/team/app:v1",
+			want:     "registry.example.com/compat/team/app:v1",
+		},
+		{
+			name:     "digest reference without tag",
+			template: "{{.Registry}}/{{.Repository}}:{{.Tag}}-compat",
+			image:    "registry.example.com/team/app@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := registry.ParseReference(tt.image)
+			if err != nil {
+				t.Fatalf("invalid image: %v", err)
+			}
+			got, err := deriveArtifactReference(tt.template, ref)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("deriveArtifactReference() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFallbackArtifactClient(t *testing.T) {
+	fallbackSpec := &compatv1alpha1.Spec{Version: "fallback"}
+
+	client := &fallbackArtifactClient{
+		derived:  &MockArtifactClient{err: errdef.ErrNotFound},
+		fallback: &MockArtifactClient{spec: fallbackSpec},
+		image:    "registry.example.com/team/app:v1",
+	}
+	spec, err := client.FetchCompatibilitySpec(context.Background())
+	if err != nil || spec != fallbackSpec {
+		t.Errorf("expected fallback spec, got %v, %v", spec, err)
+	}
+
+	// Other errors of the derived artifact are not masked by the fallback
+	fetchErr := errors.New("connection refused")
+	client.derived = &MockArtifactClient{err: fetchErr}
+	if _, err := client.FetchCompatibilitySpec(context.Background()); !errors.Is(err, fetchErr) {
+		t.Errorf("expected derived fetch error, got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	nfdclientset "sigs.k8s.io/node-feature-discovery/api/generated/clientset/versioned"
+)
+
+// NFGImageHashLabel marks NodeFeatureGroups managed out of band for an image,
+// see imageHashLabelValue. Such groups are used instead of creating groups
+// from the image's compatibility artifact with UseExistingNodeFeatureGroups.
+const NFGImageHashLabel = "image-compat.scheduler/image-hash"
+
+// imageHashLabelValue returns the NFGImageHashLabel value of an image: the
+// first 63 hex characters of the SHA-256 of the image reference, as image
+// references do not fit label values, e.g.
+// `echo -n "$IMAGE" | sha256sum | cut -c1-63`.
+func imageHashLabelValue(imageName string) string {
+	sum := sha256.Sum256([]byte(imageName))
+	return hex.EncodeToString(sum[:])[:63]
+}
+
+// existingNFGNames returns the names of the NodeFeatureGroups labelled for
+// the image, or none if there are no such groups.
+func existingNFGNames(ctx context.Context, cli nfdclientset.Interface, namespace, imageName string) ([]string, error) {
+	nfgs, err := cli.NfdV1alpha1().NodeFeatureGroups(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: NFGImageHashLabel + "=" + imageHashLabelValue(imageName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list existing NodeFeatureGroups of image %s: %w", imageName, err)
+	}
+	names := make([]string, 0, len(nfgs.Items))
+	for _, nfg := range nfgs.Items {
+		names = append(names, nfg.Name)
+	}
+	return names, nil
+}
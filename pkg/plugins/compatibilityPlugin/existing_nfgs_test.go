@@ -0,0 +1,67 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+	nfdfake "sigs.k8s.io/node-feature-discovery/api/generated/clientset/versioned/fake"
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
+)
+
+func TestExistingNFGNames(t *testing.T) {
+	labelled := func(name, image string) *nfdv1alpha1.NodeFeatureGroup {
+		return &nfdv1alpha1.NodeFeatureGroup{ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "nfd",
+			Labels:    map[string]string{NFGImageHashLabel: imageHashLabelValue(image)},
+		}}
+	}
+	groups := []*nfdv1alpha1.NodeFeatureGroup{
+		labelled("app-gpu", "registry.example.com/app:v1"),
+		labelled("app-cpu", "registry.example.com/app:v1"),
+		labelled("other", "registry.example.com/other:v1"),
+	}
+	// The fake clientset cannot list NodeFeatureGroups, list them in a reactor
+	nfdCli := nfdfake.NewSimpleClientset()
+	nfdCli.PrependReactor("list", "nodefeaturegroups", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		selector := action.(k8stesting.ListAction).GetListRestrictions().Labels
+		list := &nfdv1alpha1.NodeFeatureGroupList{}
+		for _, nfg := range groups {
+			if selector.Matches(labels.Set(nfg.Labels)) {
+				list.Items = append(list.Items, *nfg)
+			}
+		}
+		return true, list, nil
+	})
+	ctx := context.Background()
+
+	names, err := existingNFGNames(ctx, nfdCli, "nfd", "registry.example.com/app:v1")
+	if err != nil {
+		t.Fatalf("existingNFGNames: %v", err)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"app-cpu", "app-gpu"}) {
+		t.Errorf("expected the groups labelled for the image, got %v", names)
+	}
+
+	names, err = existingNFGNames(ctx, nfdCli, "nfd", "registry.example.com/app:v2")
+	if err != nil {
+		t.Fatalf("existingNFGNames: %v", err)
+	}
+	if len(names) != 0 {
+		t.Errorf("expected no groups for an image without any, got %v", names)
+	}
+}
+
+func TestImageHashLabelValue(t *testing.T) {
+	// echo -n registry.example.com/app:v1 | sha256sum | cut -c1-63
+	want := "7feaa9c2844f4a3c377758691f3175fb27bb3e983527ff95c58a278babae5d4"
+	if got := imageHashLabelValue("registry.example.com/app:v1"); got != want {
+		t.Errorf("expected label value %s, got %s", want, got)
+	}
+}
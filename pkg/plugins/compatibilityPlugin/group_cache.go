@@ -0,0 +1,80 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
+)
+
+// groupCache caches the compatibility sets of images evaluated in-process
+// (instance type or custom features), the counterpart of the NFG cache for
+// evaluations that do not create NodeFeatureGroups.
+type groupCache struct {
+	mu      sync.RWMutex
+	entries map[string]groupCacheEntry
+}
+
+// groupCacheEntry holds the compatibility sets of an image and when they stop
+// being reused. An image without sets has no compatibility requirements.
+type groupCacheEntry struct {
+	groups    []nfdv1alpha1.NodeFeatureGroup
+	expiresAt time.Time // Zero means the entry never expires
+}
+
+func newGroupCache() *groupCache {
+	return &groupCache{entries: make(map[string]groupCacheEntry)}
+}
+
+// get returns the compatibility sets of image unless they expired.
+func (c *groupCache) get(image string) ([]nfdv1alpha1.NodeFeatureGroup, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.entries[image]
+	if !ok || (!entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt)) {
+		return nil, false
+	}
+	return entry.groups, true
+}
+
+// set stores the compatibility sets of image, expiring after ttl unless zero.
+func (c *groupCache) set(image string, groups []nfdv1alpha1.NodeFeatureGroup, ttl time.Duration) {
+	entry := groupCacheEntry{groups: groups}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[image] = entry
+	// Expired entries are dropped on writes, the cache only grows with images
+	for key, e := range c.entries {
+		if !e.expiresAt.IsZero() && time.Now().After(e.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// cachedCompatibilityGroups returns the compatibility sets of the image for
+// in-process evaluation, fetching its artifact only when they are not cached
+// under the image cache key. They are cached with the TTL of the NFG cache.
+func (f *ImageCompatibilityPlugin) cachedCompatibilityGroups(ctx context.Context, image string) ([]nfdv1alpha1.NodeFeatureGroup, error) {
+	cacheKey := f.imageCacheKey(ctx, image)
+	if groups, found := f.imageToGroupsCache.get(cacheKey); found {
+		return groups, nil
+	}
+
+	groups, err := f.fetchGroups(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	f.artifactHistory.record(image, groups)
+	f.imageToGroupsCache.set(cacheKey, groups, f.resolveCacheTTL(cacheKey))
+	if len(groups) == 0 {
+		log.Printf("Cached no compatibility requirements for image %s", image)
+	} else {
+		log.Printf("Cached %d compatibility sets for image %s", len(groups), image)
+	}
+	return groups, nil
+}
@@ -0,0 +1,71 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
+)
+
+func TestCachedCompatibilityGroups(t *testing.T) {
+	digestRef := "docker.io/library/app@sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	fetched := map[string]int{}
+	plugin := &ImageCompatibilityPlugin{
+		args: ImageCompatibilityPluginArgs{
+			DedupeByDigest: true,
+			CacheTTLOverrides: []CacheTTLOverride{
+				{TagPattern: "latest", TTL: metav1.Duration{Duration: time.Nanosecond}},
+			},
+		},
+		imageToGroupsCache: newGroupCache(),
+		tagDigests:         newDigestIndex(),
+		digestResolver: func(_ context.Context, image string) (string, error) {
+			if image == "docker.io/library/app:v1" || image == "docker.io/library/app:stable" {
+				return digestRef, nil
+			}
+			return image, nil
+		},
+		fetchGroups: func(_ context.Context, image string) ([]nfdv1alpha1.NodeFeatureGroup, error) {
+			fetched[image]++
+			if image == "docker.io/library/plain:v1" {
+				return nil, nil
+			}
+			return []nfdv1alpha1.NodeFeatureGroup{vendorGroup("Intel")}, nil
+		},
+	}
+	ctx := context.Background()
+
+	// Tags of the same digest share the fetched sets
+	for _, image := range []string{"docker.io/library/app:v1", "docker.io/library/app:stable"} {
+		groups, err := plugin.cachedCompatibilityGroups(ctx, image)
+		if err != nil || len(groups) != 1 {
+			t.Fatalf("cachedCompatibilityGroups(%s) = %v, %v", image, groups, err)
+		}
+	}
+	if fetched["docker.io/library/app:v1"] != 1 || fetched["docker.io/library/app:stable"] != 0 {
+		t.Errorf("expected a single fetch for the digest, got %v", fetched)
+	}
+
+	// Images without requirements are cached too
+	for range 2 {
+		if _, err := plugin.cachedCompatibilityGroups(ctx, "docker.io/library/plain:v1"); err != nil {
+			t.Fatalf("cachedCompatibilityGroups failed: %v", err)
+		}
+	}
+	if fetched["docker.io/library/plain:v1"] != 1 {
+		t.Errorf("expected no requirements to be cached, fetched %d times", fetched["docker.io/library/plain:v1"])
+	}
+
+	// Expired sets are fetched again
+	for range 2 {
+		if _, err := plugin.cachedCompatibilityGroups(ctx, "docker.io/library/app:latest"); err != nil {
+			t.Fatalf("cachedCompatibilityGroups failed: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if fetched["docker.io/library/app:latest"] != 2 {
+		t.Errorf("expected expired sets to be fetched again, fetched %d times", fetched["docker.io/library/app:latest"])
+	}
+}
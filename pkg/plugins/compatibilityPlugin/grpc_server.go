@@ -0,0 +1,129 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"log"
+	"net"
+	"slices"
+
+	"custom-scheduler/pkg/plugins/compatibilityPlugin/compatibilitypb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// compatibilityServer serves the Compatibility service of
+// compatibilitypb/compatibility.proto with the plugin's evaluation.
+type compatibilityServer struct {
+	compatibilitypb.UnimplementedCompatibilityServer
+	plugin *ImageCompatibilityPlugin
+}
+
+// ValidateImage evaluates the image against the node.
+func (s *compatibilityServer) ValidateImage(ctx context.Context, req *compatibilitypb.ValidateImageRequest) (*compatibilitypb.ValidateImageResponse, error) {
+	if req.GetImage() == "" || req.GetNode() == "" {
+		return nil, status.Error(codes.InvalidArgument, "image and node are required")
+	}
+	compatible, err := s.plugin.simulateNode(ctx, req.GetImage(), req.GetNode())
+	if apierrors.IsNotFound(err) {
+		return nil, status.Errorf(codes.NotFound, "node %s not found", req.GetNode())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "simulate image %s failed: %v", req.GetImage(), err)
+	}
+	return &compatibilitypb.ValidateImageResponse{Image: req.GetImage(), Node: req.GetNode(), Compatible: compatible}, nil
+}
+
+// GetCompatibilityMatrix evaluates each image against the selected nodes.
+func (s *compatibilityServer) GetCompatibilityMatrix(ctx context.Context, req *compatibilitypb.GetCompatibilityMatrixRequest) (*compatibilitypb.GetCompatibilityMatrixResponse, error) {
+	f := s.plugin
+	resp := &compatibilitypb.GetCompatibilityMatrixResponse{}
+	for _, image := range req.GetImages() {
+		row := &compatibilitypb.CompatibilityRow{}
+		var err error
+		if req.GetByArchitecture() {
+			row, err = f.architectureRow(ctx, image, req.GetNodeSelector())
+		} else {
+			row.CompatibleNodes, row.IncompatibleNodes, err = f.simulate(ctx, image, req.GetNodeSelector())
+		}
+		if err == nil && req.GetTopologyKey() != "" {
+			row.Domains, err = f.domainRows(ctx, image, req.GetNodeSelector(), req.GetTopologyKey())
+		}
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "simulate image %s failed: %v", image, err)
+		}
+		row.Image = image
+		resp.Rows = append(resp.Rows, row)
+	}
+	return resp, nil
+}
+
+// architectureRow evaluates the image per node architecture, the nodes of
+// all architectures making up the compatible and incompatible nodes.
+func (f *ImageCompatibilityPlugin) architectureRow(ctx context.Context, image, nodeSelector string) (*compatibilitypb.CompatibilityRow, error) {
+	archs, err := f.simulateArchitectures(ctx, image, nodeSelector)
+	if err != nil {
+		return nil, err
+	}
+	row := &compatibilitypb.CompatibilityRow{}
+	for _, arch := range archs {
+		row.CompatibleNodes = append(row.CompatibleNodes, arch.CompatibleNodes...)
+		row.IncompatibleNodes = append(row.IncompatibleNodes, arch.IncompatibleNodes...)
+		row.Architectures = append(row.Architectures, &compatibilitypb.ArchitectureCompatibility{
+			Architecture:      arch.Architecture,
+			Image:             arch.Image,
+			CompatibleNodes:   arch.CompatibleNodes,
+			IncompatibleNodes: arch.IncompatibleNodes,
+		})
+	}
+	slices.Sort(row.CompatibleNodes)
+	slices.Sort(row.IncompatibleNodes)
+	return row, nil
+}
+
+// domainRows evaluates the image per domain of the topology key.
+func (f *ImageCompatibilityPlugin) domainRows(ctx context.Context, image, nodeSelector, topologyKey string) ([]*compatibilitypb.DomainCompatibility, error) {
+	domains, err := f.simulateTopologyDomains(ctx, image, nodeSelector, topologyKey)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]*compatibilitypb.DomainCompatibility, 0, len(domains))
+	for _, domain := range domains {
+		rows = append(rows, &compatibilitypb.DomainCompatibility{
+			Domain:     domain.Domain,
+			Compatible: domain.Compatible,
+			Nodes:      domain.Nodes,
+		})
+	}
+	return rows, nil
+}
+
+// newGRPCServer returns a gRPC server serving the Compatibility service.
+func (f *ImageCompatibilityPlugin) newGRPCServer() *grpc.Server {
+	server := grpc.NewServer()
+	compatibilitypb.RegisterCompatibilityServer(server, &compatibilityServer{plugin: f})
+	return server
+}
+
+// startGRPCServer serves the Compatibility gRPC service until ctx is done.
+func (f *ImageCompatibilityPlugin) startGRPCServer(ctx context.Context, addr string) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Printf("Failed to listen on %s for the gRPC server: %v", addr, err)
+		return
+	}
+
+	server := f.newGRPCServer()
+
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+
+	log.Printf("Serving %s gRPC API on %s", PluginName, addr)
+	if err := server.Serve(lis); err != nil {
+		log.Printf("gRPC server on %s stopped: %v", addr, err)
+	}
+}
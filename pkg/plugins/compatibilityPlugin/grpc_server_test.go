@@ -0,0 +1,44 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"custom-scheduler/pkg/plugins/compatibilityPlugin/compatibilitypb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestGRPCServer(t *testing.T) {
+	lis := bufconn.Listen(1 << 20)
+	server := (&ImageCompatibilityPlugin{}).newGRPCServer()
+	go func() { _ = server.Serve(lis) }()
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer conn.Close()
+	client := compatibilitypb.NewCompatibilityClient(conn)
+
+	// Requests go through the generated stubs and the default codec
+	_, err = client.ValidateImage(context.Background(), &compatibilitypb.ValidateImageRequest{Image: "docker.io/library/app:v1"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument without a node, got %v", err)
+	}
+	resp, err := client.GetCompatibilityMatrix(context.Background(), &compatibilitypb.GetCompatibilityMatrixRequest{})
+	if err != nil {
+		t.Fatalf("GetCompatibilityMatrix failed: %v", err)
+	}
+	if len(resp.GetRows()) != 0 {
+		t.Errorf("expected no rows without images, got %v", resp.GetRows())
+	}
+}
@@ -0,0 +1,62 @@
+package compatibilityPlugin
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DefaultHealthFailureThreshold is the number of consecutive infrastructure
+// failures after which the plugin reports itself as not ready.
+const DefaultHealthFailureThreshold = 5
+
+// healthTracker keeps track of the plugin's ability to evaluate images.
+type healthTracker struct {
+	mu                  sync.Mutex
+	threshold           int
+	startupErr          error
+	consecutiveFailures int
+	lastErr             error
+}
+
+// newHealthTracker creates a healthTracker. A non-nil startupErr keeps the
+// tracker unhealthy until the first successful scheduling cycle.
+func newHealthTracker(threshold int, startupErr error) *healthTracker {
+	if threshold <= 0 {
+		threshold = DefaultHealthFailureThreshold
+	}
+	return &healthTracker{
+		threshold:  threshold,
+		startupErr: startupErr,
+	}
+}
+
+// recordSuccess resets the failure counters after a successful evaluation.
+func (h *healthTracker) recordSuccess() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.startupErr = nil
+	h.consecutiveFailures = 0
+	h.lastErr = nil
+}
+
+// recordFailure records an infrastructure failure.
+func (h *healthTracker) recordFailure(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.consecutiveFailures++
+	h.lastErr = err
+}
+
+// check returns an error when the startup self-check failed or when the
+// number of consecutive failures reached the threshold.
+func (h *healthTracker) check() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.startupErr != nil {
+		return fmt.Errorf("startup self-check failed: %w", h.startupErr)
+	}
+	if h.consecutiveFailures >= h.threshold {
+		return fmt.Errorf("%d consecutive infrastructure failures, last error: %w", h.consecutiveFailures, h.lastErr)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package compatibilityPlugin
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHealthTracker(t *testing.T) {
+	h := newHealthTracker(2, errors.New("nfd-master pod not found"))
+	if err := h.check(); err == nil {
+		t.Fatal("expected startup self-check failure to be reported")
+	}
+
+	h.recordSuccess()
+	if err := h.check(); err != nil {
+		t.Fatalf("expected healthy after success, got %v", err)
+	}
+
+	h.recordFailure(errors.New("apiserver unavailable"))
+	if err := h.check(); err != nil {
+		t.Fatalf("expected healthy below threshold, got %v", err)
+	}
+
+	h.recordFailure(errors.New("apiserver unavailable"))
+	if err := h.check(); err == nil {
+		t.Fatal("expected unhealthy once threshold is reached")
+	}
+
+	h.recordSuccess()
+	if err := h.check(); err != nil {
+		t.Fatalf("expected healthy after recovery, got %v", err)
+	}
+}
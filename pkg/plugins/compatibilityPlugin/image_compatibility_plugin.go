@@ -3,19 +3,40 @@ package compatibilityPlugin
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
 	k8sclient "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	v1helper "k8s.io/component-helpers/scheduling/corev1"
+	"k8s.io/klog/v2"
 	fwk "k8s.io/kube-scheduler/framework"
 	framework "k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/parallelize"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+	schedutil "k8s.io/kubernetes/pkg/scheduler/util"
+	"oras.land/oras-go/v2/errdef"
 	"oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote/errcode"
 	nfdclientset "sigs.k8s.io/node-feature-discovery/api/generated/clientset/versioned"
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
 	artifactcli "sigs.k8s.io/node-feature-discovery/pkg/client-nfd/compat/artifact-client"
 )
 
@@ -33,45 +54,391 @@ func New(ctx context.Context, configuration runtime.Object, handle framework.Han
 			return nil, fmt.Errorf("failed to unmarshal plugin configuration: %w", err)
 		}
 	}
+	if err := validateArgs(&args); err != nil {
+		return nil, fmt.Errorf("invalid plugin configuration: %w", err)
+	}
 
-	// Initialize NFD client for accessing NodeFeatureGroup CRs.
-	var (
-		nfdCli nfdclientset.Interface
-	)
+	// Without the NodeFeatureGroup API only the evaluation that does not
+	// create NodeFeatureGroups is available. Fail fast when none is
+	// configured, instead of failing every scheduling cycle.
+	nfgUnavailable := checkNfdAPIAvailable(handle.ClientSet().Discovery())
+	if nfgUnavailable != nil {
+		if !worksWithoutNodeFeatureGroups(args) {
+			return nil, nfgUnavailable
+		}
+		log.Printf("WARNING: %v, NodeFeatureGroup evaluation is disabled", nfgUnavailable)
+	}
 
-	// Scheduler usually runs in-cluster as a Pod, so use InClusterConfig.
-	restCfg, err := rest.InClusterConfig()
+	// Initialize NFD client for accessing NodeFeatureGroup CRs. This is best
+	// effort, construction is retried on first use if it fails here.
+	var startupErr error
+	nfdCli, err := newNfdClient()
 	if err != nil {
-		log.Printf("failed to create in-cluster config for nfd client: %v", err)
-	} else {
-		if cli, err := nfdclientset.NewForConfig(restCfg); err != nil {
-			log.Printf("failed to create nfd clientset: %v", err)
-		} else {
-			nfdCli = cli
+		log.Printf("WARNING: %v, NodeFeatureGroup evaluation is disabled until the client can be created", err)
+		startupErr = err
+	}
+
+	// Dynamically discover nfd-master namespace, which only NodeFeatureGroup
+	// evaluation needs
+	var nfdMasterNamespace string
+	if nfgUnavailable == nil {
+		nfdMasterNamespace, err = discoverNfdMasterNamespace(ctx, handle.ClientSet())
+		if err != nil {
+			log.Printf("failed to discover nfd-master namespace: %v, will retry on first use", err)
+			// Continue with empty namespace, will be discovered lazily
+			if startupErr == nil {
+				startupErr = err
+			}
+		} else if nfdMasterNamespace == "" && startupErr == nil {
+			startupErr = errors.New("nfd-master pod not found")
 		}
 	}
 
-	// Dynamically discover nfd-master namespace
-	nfdMasterNamespace, err := discoverNfdMasterNamespace(ctx, handle.ClientSet())
-	if err != nil {
-		log.Printf("failed to discover nfd-master namespace: %v, will retry on first use", err)
-		// Continue with empty namespace, will be discovered lazily
+	// Bound concurrent work by the scheduler's configured parallelism
+	parallelizer := handle.Parallelizer()
+	if parallelizer == (parallelize.Parallelizer{}) {
+		log.Printf("scheduler parallelizer not available, using default parallelism %d", parallelize.DefaultParallelism)
+		parallelizer = parallelize.NewParallelizer(parallelize.DefaultParallelism)
 	}
 
 	plugin := &ImageCompatibilityPlugin{
 		handle:             handle,
+		parallelizer:       parallelizer,
 		nfdClient:          nfdCli,
 		nfdMasterNamespace: nfdMasterNamespace,
+		nfgUnavailable:     nfgUnavailable,
 		args:               args,
-		imageToNFGCache:    make(map[string][]string),
+		imageResolver:      newPrefixImageResolver(args.ImageRewrites),
+		health:             newHealthTracker(args.HealthFailureThreshold, startupErr),
+		artifactHistory:    newArtifactHistory(args.RetainLastArtifacts),
+		decisions:          newDecisionHistory(args.RetainLastDecisions),
+		imageToNFGCache:    newNFGCache(),
+		imageToGroupsCache: newGroupCache(),
+		tagDigests:         newDigestIndex(),
+		requiredNFGs:       newRequiredNFGIndex(),
+	}
+	plugin.digestResolver = func(ctx context.Context, image string) (string, error) {
+		return resolveImageDigest(ctx, handle.ClientSet(), image, args)
+	}
+	plugin.fetchGroups = func(ctx context.Context, image string) ([]nfdv1alpha1.NodeFeatureGroup, error) {
+		return fetchCompatibilityGroups(ctx, handle.ClientSet(), image, args)
+	}
+
+	if args.CustomFeaturesConfigMap != "" {
+		if nfdCli == nil {
+			log.Printf("WARNING: nfd client unavailable, custom features from %s are disabled", args.CustomFeaturesConfigMap)
+		} else if plugin.customFeatures, err = newCustomFeatureSource(ctx, handle.ClientSet(), nfdCli, args); err != nil {
+			return nil, fmt.Errorf("failed to watch custom features: %w", err)
+		}
+	}
+
+	if args.RetainLastDecisions > 0 {
+		if nfdCli == nil {
+			log.Printf("WARNING: nfd client unavailable, decisions are recorded without NodeFeature snapshots")
+		} else {
+			plugin.nodeFeatures, plugin.nodeFeaturesSynced = newNodeFeatureLister(ctx, nfdCli)
+		}
+	}
+
+	if args.RejectionReasonTemplate != "" {
+		if plugin.reasonTemplate, err = parseRejectionReasonTemplate(args.RejectionReasonTemplate); err != nil {
+			return nil, fmt.Errorf("invalid rejectionReasonTemplate: %w", err)
+		}
+	}
+
+	if args.ResultOwners {
+		plugin.ownerReferences = newInformerOwnerReferences(handle.SharedInformerFactory())
+	}
+
+	if args.RevalidationAnnotation != "" {
+		if err := plugin.watchNodeRevalidation(ctx); err != nil {
+			return nil, fmt.Errorf("failed to watch node revalidation annotation: %w", err)
+		}
+	}
+
+	if args.NodeVerdictLabels {
+		plugin.nodeVerdicts = newNodeVerdictQueue()
+		plugin.nodeVerdicts.run(ctx, plugin)
+	}
+
+	if plugin.resultWebhook, err = newResultWebhook(handle.ClientSet(), args); err != nil {
+		return nil, fmt.Errorf("invalid result webhook: %w", err)
+	}
+	if plugin.resultWebhook != nil {
+		go plugin.resultWebhook.run(ctx)
+	}
+
+	if args.CacheFile != "" {
+		plugin.loadCacheFile(args.CacheFile)
+		go plugin.runCachePersistence(ctx, args.CacheFile, cacheFlushInterval(args))
 	}
 
 	// Start background cleanup goroutine
-	go plugin.startNFGCleanup(ctx)
+	if nfgUnavailable == nil {
+		go plugin.startNFGCleanup(ctx)
+	}
+
+	if args.BindAddress != "" {
+		go plugin.startHTTPServer(ctx, args.BindAddress)
+	}
+	if args.GRPCBindAddress != "" {
+		go plugin.startGRPCServer(ctx, args.GRPCBindAddress)
+	}
+
+	plugin.logStartupSummary()
 
 	return plugin, nil
 }
 
+// NewRestConfig returns the client config for the kubeconfig file at path. With
+// an empty path the in-cluster config is used, as the scheduler usually runs
+// in-cluster as a Pod, falling back to $KUBECONFIG or ~/.kube/config when
+// running out of cluster.
+func NewRestConfig(path string) (*rest.Config, error) {
+	if path == "" {
+		restCfg, err := rest.InClusterConfig()
+		if err == nil {
+			return restCfg, nil
+		}
+		if !errors.Is(err, rest.ErrNotInCluster) {
+			return nil, fmt.Errorf("failed to create in-cluster config: %w", err)
+		}
+	}
+
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = path
+	restCfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	return restCfg, nil
+}
+
+// newNfdClient creates the NFD clientset.
+func newNfdClient() (nfdclientset.Interface, error) {
+	restCfg, err := NewRestConfig("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config for nfd client: %w", err)
+	}
+	cli, err := nfdclientset.NewForConfig(restCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create nfd clientset: %w", err)
+	}
+	return cli, nil
+}
+
+// getNfdClient returns the NFD clientset, creating it if the construction
+// failed during plugin initialization.
+func (f *ImageCompatibilityPlugin) getNfdClient() (nfdclientset.Interface, error) {
+	f.nfdClientMutex.RLock()
+	cli := f.nfdClient
+	f.nfdClientMutex.RUnlock()
+	if cli != nil {
+		return cli, nil
+	}
+
+	f.nfdClientMutex.Lock()
+	defer f.nfdClientMutex.Unlock()
+	if f.nfdClient != nil {
+		return f.nfdClient, nil
+	}
+	cli, err := newNfdClient()
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("nfd client created, NodeFeatureGroup evaluation is enabled")
+	f.nfdClient = cli
+	return cli, nil
+}
+
+// logStartupSummary logs which plugin features are enabled.
+func (f *ImageCompatibilityPlugin) logStartupSummary() {
+	enabled := func(on bool) string {
+		if on {
+			return "enabled"
+		}
+		return "disabled"
+	}
+	f.nfdClientMutex.RLock()
+	nfdAvailable := f.nfdClient != nil && f.nfgUnavailable == nil
+	f.nfdClientMutex.RUnlock()
+
+	log.Printf("%s started: NodeFeatureGroup evaluation %s, nfd-master namespace %q, HTTP endpoints %s, "+
+		"cache TTL %v with %d tag overrides, %d image rewrites, %d registry pull secrets",
+		PluginName, enabled(nfdAvailable), f.nfdMasterNamespace, enabled(f.args.BindAddress != ""),
+		f.args.CacheTTL.Duration, len(f.args.CacheTTLOverrides), len(f.args.ImageRewrites), len(f.args.RegistryPullSecrets))
+}
+
+// NewWithImageResolver returns a plugin factory that evaluates the images
+// returned by resolver instead of the ones declared in the pod spec.
+func NewWithImageResolver(resolver ImageResolver) frameworkruntime.PluginFactory {
+	return func(ctx context.Context, configuration runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+		p, err := New(ctx, configuration, handle)
+		if err != nil {
+			return nil, err
+		}
+		p.(*ImageCompatibilityPlugin).imageResolver = resolver
+		return p, nil
+	}
+}
+
+// newPrefixImageResolver returns an ImageResolver applying the first matching
+// prefix rewrite, or the identity resolver when there are no rewrites.
+func newPrefixImageResolver(rewrites []ImageRewrite) ImageResolver {
+	return func(_ context.Context, _ *v1.Pod, image string) (string, error) {
+		for _, r := range rewrites {
+			if strings.HasPrefix(image, r.From) {
+				return r.To + strings.TrimPrefix(image, r.From), nil
+			}
+		}
+		return image, nil
+	}
+}
+
+// validateArgs checks the plugin arguments for values that cannot be used.
+func validateArgs(args *ImageCompatibilityPluginArgs) error {
+	if args.HealthFailureThreshold < 0 {
+		return fmt.Errorf("healthFailureThreshold must not be negative, got %d", args.HealthFailureThreshold)
+	}
+	if args.ArtifactFetchTimeout.Duration < 0 {
+		return fmt.Errorf("artifactFetchTimeout must not be negative, got %v", args.ArtifactFetchTimeout.Duration)
+	}
+	if args.CacheFlushInterval.Duration < 0 {
+		return fmt.Errorf("cacheFlushInterval must not be negative, got %v", args.CacheFlushInterval.Duration)
+	}
+	if args.ArtifactFetchAttempts < 0 {
+		return fmt.Errorf("artifactFetchAttempts must not be negative, got %d", args.ArtifactFetchAttempts)
+	}
+	if args.MaxNodeVerdictLabels < 0 {
+		return fmt.Errorf("maxNodeVerdictLabels must not be negative, got %d", args.MaxNodeVerdictLabels)
+	}
+	if args.RetainLastArtifacts < 0 {
+		return fmt.Errorf("retainLastArtifacts must not be negative, got %d", args.RetainLastArtifacts)
+	}
+	if args.RetainLastDecisions < 0 {
+		return fmt.Errorf("retainLastDecisions must not be negative, got %d", args.RetainLastDecisions)
+	}
+	if args.PerImageTimeout.Duration < 0 {
+		return fmt.Errorf("perImageTimeout must not be negative, got %v", args.PerImageTimeout.Duration)
+	}
+	if args.PreFilterTimeout.Duration < 0 {
+		return fmt.Errorf("preFilterTimeout must not be negative, got %v", args.PreFilterTimeout.Duration)
+	}
+	if args.CacheTTL.Duration < 0 {
+		return fmt.Errorf("cacheTTL must not be negative, got %v", args.CacheTTL.Duration)
+	}
+	if args.ArtifactReferenceTemplate != "" {
+		if _, err := parseArtifactReferenceTemplate(args.ArtifactReferenceTemplate); err != nil {
+			return fmt.Errorf("invalid artifactReferenceTemplate: %w", err)
+		}
+	}
+	if args.RejectionReasonTemplate != "" {
+		if _, err := parseRejectionReasonTemplate(args.RejectionReasonTemplate); err != nil {
+			return fmt.Errorf("invalid rejectionReasonTemplate: %w", err)
+		}
+	}
+	if args.CheckSandboxImage && args.SandboxImage == "" && len(args.RuntimeClassSandboxImages) == 0 {
+		return fmt.Errorf("checkSandboxImage requires sandboxImage or runtimeClassSandboxImages")
+	}
+	if args.RevalidationAnnotation != "" && !args.NodeVerdictLabels {
+		return fmt.Errorf("revalidationAnnotation requires nodeVerdictLabels")
+	}
+	for _, c := range args.PullThroughCaches {
+		if c.Cache == "" || c.Origin == "" {
+			return fmt.Errorf("pullThroughCaches entries need both cache and origin, got %+v", c)
+		}
+	}
+	if args.MinMatchRatio != nil && (*args.MinMatchRatio < 0 || *args.MinMatchRatio > 1) {
+		return fmt.Errorf("minMatchRatio must be between 0 and 1, got %v", *args.MinMatchRatio)
+	}
+	switch args.ResultLogFormat {
+	case "", ResultLogFormatText, ResultLogFormatJSON:
+	default:
+		return fmt.Errorf("resultLogFormat must be %q or %q, got %q", ResultLogFormatText, ResultLogFormatJSON, args.ResultLogFormat)
+	}
+	if args.InstanceTypeFeaturesConfigMap != "" {
+		if _, err := parseNamespacedName(args.InstanceTypeFeaturesConfigMap); err != nil {
+			return fmt.Errorf("invalid instanceTypeFeaturesConfigMap: %w", err)
+		}
+	}
+	if args.ResultWebhookURL != "" {
+		if u, err := url.Parse(args.ResultWebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid resultWebhookURL %q", args.ResultWebhookURL)
+		}
+	}
+	if args.ResultWebhookTimeout.Duration < 0 {
+		return fmt.Errorf("resultWebhookTimeout must not be negative, got %v", args.ResultWebhookTimeout.Duration)
+	}
+	if args.ResultWebhookAuthSecret != "" {
+		if _, err := parseNamespacedName(args.ResultWebhookAuthSecret); err != nil {
+			return fmt.Errorf("invalid resultWebhookAuthSecret: %w", err)
+		}
+	}
+	if args.OfflineArtifactConfigMap != "" {
+		if _, err := parseNamespacedName(args.OfflineArtifactConfigMap); err != nil {
+			return fmt.Errorf("invalid offlineArtifactConfigMap: %w", err)
+		}
+	}
+	if args.MaxReasonLength < 0 {
+		return fmt.Errorf("maxReasonLength must not be negative, got %d", args.MaxReasonLength)
+	}
+	if args.CustomFeaturesConfigMap != "" {
+		if _, err := parseNamespacedName(args.CustomFeaturesConfigMap); err != nil {
+			return fmt.Errorf("invalid customFeaturesConfigMap: %w", err)
+		}
+	}
+	for host, secretRef := range args.RegistryPullSecrets {
+		if _, err := parseNamespacedName(secretRef); err != nil {
+			return fmt.Errorf("invalid registryPullSecrets entry for %s: %w", host, err)
+		}
+	}
+	for _, r := range args.ImageRewrites {
+		if r.From == "" {
+			return fmt.Errorf("imageRewrites entry with empty from prefix")
+		}
+	}
+	for _, o := range args.CacheTTLOverrides {
+		if _, err := path.Match(o.TagPattern, ""); err != nil {
+			return fmt.Errorf("invalid cacheTTLOverrides tagPattern %q: %w", o.TagPattern, err)
+		}
+		if o.TTL.Duration < 0 {
+			return fmt.Errorf("cacheTTLOverrides ttl for %q must not be negative, got %v", o.TagPattern, o.TTL.Duration)
+		}
+	}
+	return nil
+}
+
+// checkNfdAPIAvailable verifies that the NFD API group is served by the
+// cluster and that it includes the NodeFeatureGroup resource. Only a missing
+// API or resource fails, other discovery errors are logged.
+func checkNfdAPIAvailable(discoveryClient discovery.DiscoveryInterface) error {
+	groupVersion := nfdv1alpha1.SchemeGroupVersion.String()
+	resources, err := discoveryClient.ServerResourcesForGroupVersion(groupVersion)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("NFD API %s is not served by the cluster, please install Node Feature Discovery and its CRDs", groupVersion)
+		}
+		// Discovery errors such as timeouts do not mean the API is missing,
+		// the plugin reports NFD errors when it uses the API
+		log.Printf("WARNING: failed to discover NFD API %s, continuing: %v", groupVersion, err)
+		return nil
+	}
+
+	for _, r := range resources.APIResources {
+		if r.Name == NodeFeatureGroupResource {
+			return nil
+		}
+	}
+	return fmt.Errorf("NFD API %s does not serve %s, please upgrade the Node Feature Discovery CRDs", groupVersion, NodeFeatureGroupResource)
+}
+
+// worksWithoutNodeFeatureGroups reports whether args configure a mode that
+// evaluates pods without the NodeFeatureGroup API: instance type features,
+// custom features or an offline artifact bundle.
+func worksWithoutNodeFeatureGroups(args ImageCompatibilityPluginArgs) bool {
+	return args.InstanceTypeFeaturesConfigMap != "" || args.CustomFeaturesConfigMap != "" || args.OfflineArtifactConfigMap != ""
+}
+
 // discoverNfdMasterNamespace finds the namespace where nfd-master is running
 // by searching for pods with the nfd-master label selector.
 func discoverNfdMasterNamespace(ctx context.Context, clientSet k8sclient.Interface) (string, error) {
@@ -126,32 +493,175 @@ func (f *ImageCompatibilityPlugin) Name() string {
 
 // PreFilter is invoked at the PreFilter extension point.
 func (f *ImageCompatibilityPlugin) PreFilter(ctx context.Context, cycleState fwk.CycleState, pod *v1.Pod, filteredNodes []fwk.NodeInfo) (*framework.PreFilterResult, *fwk.Status) {
+	// A terminating pod is not worth fetching artifacts and creating NFGs for
+	if pod.DeletionTimestamp != nil {
+		log.Printf("Pod %s/%s is being deleted, skipping compatibility evaluation", pod.Namespace, pod.Name)
+		return nil, fwk.NewStatus(fwk.Skip)
+	}
+	// A gated pod is not ready to be scheduled, it is evaluated once the
+	// gates are removed
+	if len(pod.Spec.SchedulingGates) > 0 {
+		log.Printf("Pod %s/%s has scheduling gates, skipping compatibility evaluation", pod.Namespace, pod.Name)
+		return nil, fwk.NewStatus(fwk.Skip)
+	}
+
+	// Nodes already rejected by other plugins are not worth evaluating
+	if rejected := readRejectedNodes(cycleState); len(rejected) > 0 {
+		filteredNodes = withoutRejectedNodes(filteredNodes, rejected)
+		log.Printf("Leaving %d nodes rejected by other plugins out of the evaluation of pod %s/%s", len(rejected), pod.Namespace, pod.Name)
+	}
+
+	// Bound the whole evaluation of the pod
+	if timeout := f.args.PreFilterTimeout.Duration; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Deduplicate images so that concurrent workers never race to create
+	// NFGs for the same image before the cache is populated
+	phaseImages, err := f.resolvePodImages(ctx, pod)
+	if err != nil {
+		return nil, fwk.NewStatus(fwk.Error, truncateReason(err.Error(), f.maxReasonLength()))
+	}
+	images := phaseImages.all()
+
+	// Nodes of known instance types are evaluated in-process, without NFD.
+	// Otherwise nodes with custom features are evaluated in-process.
+	source := CompatibilitySourceInstanceType
+	byPhase, preferred, ok, err := f.compatibleNodesByInstanceType(ctx, phaseImages, podRuntimeClass(pod), filteredNodes)
+	if err == nil && !ok {
+		source = CompatibilitySourceCustomFeatures
+		byPhase, preferred, ok, err = f.compatibleNodesByCustomFeatures(ctx, phaseImages, podRuntimeClass(pod), filteredNodes)
+	}
+	if err != nil {
+		var notFound *ImageNotFoundError
+		if errors.As(err, &notFound) {
+			return nil, f.imageNotFoundStatus(notFound)
+		}
+		return nil, f.infrastructureFailure("failed to evaluate images in-process", err)
+	}
+	if ok {
+		compatibleNodes, incompatiblePhases := phaseCompatibility(byPhase, filteredNodes)
+		cycleState.Write(PluginName, &CompatibilityState{
+			CompatibleNodes:    compatibleNodes,
+			IncompatiblePhases: incompatiblePhases,
+			NodeScores:         nodeScores(preferred, slices.Collect(maps.Keys(compatibleNodes))),
+		})
+		f.health.recordSuccess()
+		result := newCompatibilityResult(pod, images, source, nil, compatibleNodes, filteredNodes)
+		f.reportResult(pod, result)
+		f.recordDecision(ctx, result, filteredNodes)
+		return nil, fwk.NewStatus(fwk.Success)
+	}
+
+	// Ensure NodeFeatureGroups and the NFD client are available
+	if f.nfgUnavailable != nil {
+		return nil, f.infrastructureFailure("NodeFeatureGroup evaluation disabled", f.nfgUnavailable)
+	}
+	if _, err := f.getNfdClient(); err != nil {
+		return nil, f.infrastructureFailure("NodeFeatureGroup evaluation unavailable", err)
+	}
+
 	// Ensure nfd-master namespace is discovered
 	namespace, err := f.getNfdMasterNamespace(ctx)
 	if err != nil {
-		return nil, fwk.NewStatus(fwk.Error, fmt.Sprintf("failed to get nfd-master namespace: %v", err))
+		return nil, f.infrastructureFailure("failed to get nfd-master namespace", err)
 	}
 
 	// Create NodeFeatureGroup CRs for all container images
 	// Store created NFG names in cycle state for later reference
-	createdNFGs, err := f.createNodeFeatureGroupsForPod(ctx, pod, namespace)
+	imageNFGs, err := f.createNodeFeatureGroupsForPod(ctx, pod, images, namespace)
+	if err != nil {
+		var notFound *ImageNotFoundError
+		if errors.As(err, &notFound) {
+			return nil, f.imageNotFoundStatus(notFound)
+		}
+		return nil, f.infrastructureFailure("failed to create NodeFeatureGroups", err)
+	}
+	// Only the compatibility sets for the runtime class of the pod apply
+	imageNFGs, err = f.selectRuntimeClassNFGs(ctx, namespace, imageNFGs, podRuntimeClass(pod))
 	if err != nil {
-		return nil, fwk.NewStatus(fwk.Error, fmt.Sprintf("failed to create NodeFeatureGroups: %v", err))
+		return nil, f.infrastructureFailure("failed to get NodeFeatureGroups", err)
+	}
+	var createdNFGs []string
+	for _, image := range images {
+		createdNFGs = append(createdNFGs, imageNFGs[image]...)
 	}
 
-	// Collect compatible nodes (with retry logic built in)
-	compatibleNodes, err := f.collectCompatibleNodesFromNFGs(ctx, namespace, createdNFGs)
+	// Images without compatibility requirements run on any node
+	if len(createdNFGs) == 0 {
+		log.Printf("Images %v of pod %s/%s have no compatibility requirements", images, pod.Namespace, pod.Name)
+		f.health.recordSuccess()
+		return nil, fwk.NewStatus(fwk.Skip)
+	}
+
+	// Weighted compatibility sets only score nodes, the others filter them
+	requiredNFGs, preferredNFGs, err := f.splitByWeight(ctx, namespace, createdNFGs)
 	if err != nil {
-		return nil, fwk.NewStatus(fwk.Error, fmt.Sprintf("failed to collect compatible nodes from NFGs: %v", err))
+		return nil, f.infrastructureFailure("failed to get NodeFeatureGroups", err)
+	}
+
+	// Collect the compatible nodes of each phase (with retry logic built in),
+	// so that Filter can report whether init or main container images failed
+	byPhase = make(map[string]map[string]struct{})
+	for _, phase := range containerPhases {
+		var phaseNFGs []string
+		for _, image := range phaseImages[phase] {
+			for _, nfgName := range imageNFGs[image] {
+				if slices.Contains(requiredNFGs, nfgName) {
+					phaseNFGs = append(phaseNFGs, nfgName)
+				}
+			}
+		}
+		if len(phaseNFGs) == 0 {
+			continue
+		}
+		byPhase[phase], err = f.collectCompatibleNodesFromNFGs(ctx, namespace, phaseNFGs)
+		if err != nil {
+			return nil, f.infrastructureFailure("failed to collect compatible nodes from NFGs", err)
+		}
+	}
+	compatibleNodes, incompatiblePhases := phaseCompatibility(byPhase, filteredNodes)
+	if len(compatibleNodes) < len(filteredNodes) {
+		f.requiredNFGs.record(pod, requiredNFGs)
+	} else {
+		f.requiredNFGs.forget(pod.UID)
+	}
+
+	preferred = make([]preferredSet, 0, len(preferredNFGs))
+	for nfgName, weight := range preferredNFGs {
+		nodes, err := f.getNFGNodes(ctx, namespace, nfgName)
+		if err != nil {
+			log.Printf("Failed to get nodes of preferred NFG %s, not scoring it: %v", nfgName, err)
+			nodes = nil
+		}
+		preferred = append(preferred, preferredSet{weight: weight, nodes: nodes})
 	}
 
 	// Store NFG names and compatible nodes in cycle state for Filter phase
 	state := &CompatibilityState{
-		CompatibleNodes: compatibleNodes,
-		CreatedNFGs:     createdNFGs,
-		Namespace:       namespace,
+		CompatibleNodes:    compatibleNodes,
+		IncompatiblePhases: incompatiblePhases,
+		CreatedNFGs:        createdNFGs,
+		Namespace:          namespace,
+		NodeScores:         nodeScores(preferred, slices.Collect(maps.Keys(compatibleNodes))),
 	}
 	cycleState.Write(PluginName, state)
+	f.health.recordSuccess()
+	result := newCompatibilityResult(pod, images, CompatibilitySourceNodeFeatureGroups, createdNFGs, compatibleNodes, filteredNodes)
+	f.reportResult(pod, result)
+	f.recordDecision(ctx, result, filteredNodes)
+
+	if f.args.NodeVerdictLabels {
+		nodeNames := make([]string, 0, len(filteredNodes))
+		for _, nodeInfo := range filteredNodes {
+			if node := nodeInfo.Node(); node != nil {
+				nodeNames = append(nodeNames, node.Name)
+			}
+		}
+		f.nodeVerdicts.enqueue(nodeVerdictRequest{namespace: namespace, imageNFGs: imageNFGs, nodeNames: nodeNames})
+	}
 
 	return nil, fwk.NewStatus(fwk.Success)
 }
@@ -161,14 +671,217 @@ func (f *ImageCompatibilityPlugin) PreFilterExtensions() framework.PreFilterExte
 	return nil
 }
 
+// EventsToRegister returns the cluster events that may make a Pod rejected
+// by this plugin schedulable, so that it is requeued with backoff instead
+// of waiting for an unrelated event.
+func (f *ImageCompatibilityPlugin) EventsToRegister(_ context.Context) ([]fwk.ClusterEventWithHint, error) {
+	return []fwk.ClusterEventWithHint{
+		// New nodes or node feature label changes may provide the features
+		// required by the pod images
+		{
+			Event:          fwk.ClusterEvent{Resource: fwk.Node, ActionType: fwk.Add | fwk.UpdateNodeLabel},
+			QueueingHintFn: f.isSchedulableAfterNodeChange,
+		},
+		// nfd-master updates NodeFeatureGroup status when the set of
+		// matching nodes changes
+		{
+			Event:          fwk.ClusterEvent{Resource: NodeFeatureGroupEventResource, ActionType: fwk.Add | fwk.Update},
+			QueueingHintFn: f.isSchedulableAfterNodeFeatureGroupChange,
+		},
+	}, nil
+}
+
+// isSchedulableAfterNodeChange requeues the pod when a node is added or when
+// the NFD feature labels of a node change.
+func (f *ImageCompatibilityPlugin) isSchedulableAfterNodeChange(logger klog.Logger, pod *v1.Pod, oldObj, newObj interface{}) (fwk.QueueingHint, error) {
+	oldNode, newNode, err := schedutil.As[*v1.Node](oldObj, newObj)
+	if err != nil {
+		return fwk.Queue, err
+	}
+
+	if oldNode == nil {
+		logger.V(5).Info("node was added, pod may be compatible with it", "pod", klog.KObj(pod), "node", klog.KObj(newNode))
+		return fwk.Queue, nil
+	}
+
+	if !maps.Equal(nodeFeatureLabels(oldNode), nodeFeatureLabels(newNode)) {
+		logger.V(5).Info("node feature labels changed", "pod", klog.KObj(pod), "node", klog.KObj(newNode))
+		return fwk.Queue, nil
+	}
+
+	return fwk.QueueSkip, nil
+}
+
+// isSchedulableAfterNodeFeatureGroupChange requeues the pod when nodes are
+// added to a NodeFeatureGroup managed by this plugin that the pod requires.
+// Removed nodes cannot make the pod schedulable.
+func (f *ImageCompatibilityPlugin) isSchedulableAfterNodeFeatureGroupChange(logger klog.Logger, pod *v1.Pod, oldObj, newObj interface{}) (fwk.QueueingHint, error) {
+	newNFG, err := toNodeFeatureGroup(newObj)
+	if err != nil {
+		return fwk.Queue, err
+	}
+	if newNFG.Labels["managed-by"] != PluginName || !f.requiredNFGs.requires(pod.UID, newNFG.Name) {
+		return fwk.QueueSkip, nil
+	}
+
+	var oldNodes map[string]struct{}
+	if oldObj != nil {
+		oldNFG, err := toNodeFeatureGroup(oldObj)
+		if err != nil {
+			return fwk.Queue, err
+		}
+		oldNodes = nfgStatusNodes(oldNFG)
+	}
+
+	for node := range nfgStatusNodes(newNFG) {
+		if _, found := oldNodes[node]; !found {
+			logger.V(5).Info("node added to NodeFeatureGroup", "pod", klog.KObj(pod), "nodeFeatureGroup", klog.KObj(newNFG), "node", node)
+			return fwk.Queue, nil
+		}
+	}
+
+	return fwk.QueueSkip, nil
+}
+
+// nodeFeatureLabels returns the labels of a node published by NFD.
+func nodeFeatureLabels(node *v1.Node) map[string]string {
+	features := make(map[string]string)
+	for key, value := range node.Labels {
+		ns, _, found := strings.Cut(key, "/")
+		if !found {
+			continue
+		}
+		if ns == nfdv1alpha1.FeatureLabelNs || strings.HasSuffix(ns, nfdv1alpha1.FeatureLabelSubNsSuffix) ||
+			ns == nfdv1alpha1.ProfileLabelNs || strings.HasSuffix(ns, nfdv1alpha1.ProfileLabelSubNsSuffix) {
+			features[key] = value
+		}
+	}
+	return features
+}
+
+// nfgStatusNodes returns the set of node names listed in the NFG status.
+func nfgStatusNodes(nfg *nfdv1alpha1.NodeFeatureGroup) map[string]struct{} {
+	nodes := make(map[string]struct{}, len(nfg.Status.Nodes))
+	for _, n := range nfg.Status.Nodes {
+		nodes[n.Name] = struct{}{}
+	}
+	return nodes
+}
+
+// toNodeFeatureGroup converts an informer object into a NodeFeatureGroup.
+// Custom resources are delivered by the scheduler as unstructured objects.
+func toNodeFeatureGroup(obj interface{}) (*nfdv1alpha1.NodeFeatureGroup, error) {
+	switch o := obj.(type) {
+	case *nfdv1alpha1.NodeFeatureGroup:
+		return o, nil
+	case *unstructured.Unstructured:
+		nfg := &nfdv1alpha1.NodeFeatureGroup{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(o.UnstructuredContent(), nfg); err != nil {
+			return nil, fmt.Errorf("failed to convert %s to NodeFeatureGroup: %w", o.GetName(), err)
+		}
+		return nfg, nil
+	default:
+		return nil, fmt.Errorf("expected NodeFeatureGroup, but got %T", obj)
+	}
+}
+
+// imageNotFoundStatus reports a missing image. No node can make a missing
+// image schedulable, so it is unresolvable unless RetryMissingImages is set.
+func (f *ImageCompatibilityPlugin) imageNotFoundStatus(err *ImageNotFoundError) *fwk.Status {
+	log.Printf("%v", err)
+	code := fwk.UnschedulableAndUnresolvable
+	if f.args.RetryMissingImages {
+		code = fwk.Unschedulable
+	}
+	return fwk.NewStatus(code, fmt.Sprintf("image %s not found in registry", err.Image))
+}
+
+// isImageNotFoundError reports whether a registry error means that the image
+// manifest or its repository does not exist.
+func isImageNotFoundError(err error) bool {
+	if errors.Is(err, errdef.ErrNotFound) {
+		return true
+	}
+	var errResp *errcode.ErrorResponse
+	if errors.As(err, &errResp) {
+		for _, e := range errResp.Errors {
+			if e.Code == errcode.ErrorCodeManifestUnknown || e.Code == errcode.ErrorCodeNameUnknown {
+				return true
+			}
+		}
+		return errResp.StatusCode == http.StatusNotFound
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "manifest unknown") || strings.Contains(msg, "repository not found") ||
+		strings.Contains(msg, "name unknown")
+}
+
+// infrastructureFailure records a failure of the plugin's dependencies for
+// health reporting and converts it into a scheduler status.
+func (f *ImageCompatibilityPlugin) infrastructureFailure(msg string, err error) *fwk.Status {
+	f.health.recordFailure(err)
+	status := statusFromError(msg, err)
+	return fwk.NewStatus(status.Code(), truncateReason(status.Message(), f.maxReasonLength()))
+}
+
+// statusFromError converts a PreFilter error into a scheduler status. Errors,
+// transient or not, abort the scheduling cycle with Error so that the pod goes
+// through the backoff queue: Unschedulable would park it until a registered
+// event fires, and no event fires when the infrastructure recovers.
+func statusFromError(msg string, err error) *fwk.Status {
+	if isTransientError(err) {
+		log.Printf("%s, transient error, pod will be retried with backoff: %v", msg, err)
+	}
+	return fwk.NewStatus(fwk.Error, fmt.Sprintf("%s: %v", msg, err))
+}
+
+// isTransientError reports whether err is likely to go away on retry, such as
+// API server throttling or unavailability, timeouts and network failures.
+func isTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err) ||
+		apierrors.IsTooManyRequests(err) || apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err) {
+		return true
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
 // Filter is invoked at the Filter extension point and rejects nodes
 // that are not present in the compatible node snapshot stored in the
-// scheduling cycle state.
+// scheduling cycle state. Nodes in the RejectedNodes marker are rejected
+// before the snapshot is consulted.
 func (f *ImageCompatibilityPlugin) Filter(ctx context.Context, cycleState fwk.CycleState, pod *v1.Pod, nodeInfo fwk.NodeInfo) *fwk.Status {
 	node := nodeInfo.Node()
 	if node == nil {
-		log.Printf("NodeInfo for pod %s is nil", pod.Name)
-		return fwk.NewStatus(fwk.Error, "node not found")
+		// The node was deleted after the snapshot was taken, reject only this
+		// node rather than aborting the scheduling cycle
+		log.Printf("Node of NodeInfo for pod %s not found, it may have been deleted", pod.Name)
+		return fwk.NewStatus(fwk.Unschedulable, "node not found, it may have been deleted")
+	}
+
+	if pod.DeletionTimestamp != nil || len(pod.Spec.SchedulingGates) > 0 {
+		return fwk.NewStatus(fwk.Success)
+	}
+	// A cordoned node is rejected without looking at the compatibility state,
+	// unless the pod tolerates the unschedulable taint as in NodeUnschedulable
+	if node.Spec.Unschedulable && !v1helper.TolerationsTolerateTaint(pod.Spec.Tolerations, &v1.Taint{
+		Key:    v1.TaintNodeUnschedulable,
+		Effect: v1.TaintEffectNoSchedule,
+	}) {
+		return fwk.NewStatus(
+			fwk.Unschedulable,
+			fmt.Sprintf("node %s is unschedulable", node.Name),
+		)
+	}
+	if reason, ok := readRejectedNodes(cycleState)[node.Name]; ok {
+		return fwk.NewStatus(fwk.Unschedulable, truncateReason(rejectedNodeReason(node.Name, reason), f.maxReasonLength()))
 	}
 
 	// Get compatibility state from cycle state
@@ -178,12 +891,16 @@ func (f *ImageCompatibilityPlugin) Filter(ctx context.Context, cycleState fwk.Cy
 		return fwk.NewStatus(fwk.Error, fmt.Sprintf("get compatibility state error: %v", err))
 	}
 
+	if phases := state.IncompatiblePhases[node.Name]; len(phases) > 0 {
+		return fwk.NewStatus(fwk.Unschedulable, f.rejectionReason(pod, node.Name, phases, incompatiblePhasesReason(node.Name, phases)))
+	}
+
 	// If no compatible nodes found, reject the node
 	if len(state.CompatibleNodes) == 0 {
 		log.Printf("No compatible nodes found for pod %s", pod.Name)
 		return fwk.NewStatus(
 			fwk.Unschedulable,
-			fmt.Sprintf("node %s is not compatible with pod images", node.Name),
+			f.rejectionReason(pod, node.Name, nil, fmt.Sprintf("node %s is not compatible with pod images", node.Name)),
 		)
 	}
 
@@ -191,7 +908,7 @@ func (f *ImageCompatibilityPlugin) Filter(ctx context.Context, cycleState fwk.Cy
 	if _, ok := state.CompatibleNodes[node.Name]; !ok {
 		return fwk.NewStatus(
 			fwk.Unschedulable,
-			fmt.Sprintf("node %s is not listed in any compatible NodeFeatureGroup status", node.Name),
+			f.rejectionReason(pod, node.Name, nil, fmt.Sprintf("node %s is not listed in any compatible NodeFeatureGroup status", node.Name)),
 		)
 	}
 
@@ -229,55 +946,216 @@ func (f *ImageCompatibilityPlugin) getNfdMasterNamespace(ctx context.Context) (s
 	return namespace, nil
 }
 
+// resolvePodImages returns the deduplicated effective images of the init
+// and regular containers of the pod. Images of image volumes are part of the
+// main phase unless CheckImageVolumes is disabled, as is the sandbox image
+// with CheckSandboxImage.
+func (f *ImageCompatibilityPlugin) resolvePodImages(ctx context.Context, pod *v1.Pod) (podImages, error) {
+	byPhase := make(map[string][]string, len(containerPhases))
+	for _, container := range pod.Spec.InitContainers {
+		byPhase[ContainerPhaseInit] = append(byPhase[ContainerPhaseInit], container.Image)
+	}
+	for _, container := range pod.Spec.Containers {
+		byPhase[ContainerPhaseMain] = append(byPhase[ContainerPhaseMain], container.Image)
+	}
+	if f.args.CheckImageVolumes == nil || *f.args.CheckImageVolumes {
+		for _, volume := range pod.Spec.Volumes {
+			if volume.Image != nil && volume.Image.Reference != "" {
+				byPhase[ContainerPhaseMain] = append(byPhase[ContainerPhaseMain], volume.Image.Reference)
+			}
+		}
+	}
+	if sandbox := f.sandboxImage(pod); sandbox != "" {
+		byPhase[ContainerPhaseMain] = append(byPhase[ContainerPhaseMain], sandbox)
+	}
+
+	images := make(podImages)
+	for phase, refs := range byPhase {
+		seen := make(map[string]struct{})
+		for _, ref := range refs {
+			if f.args.SkipLocalImages && isLocalImage(ref, f.args.LocalImagePrefixes) {
+				log.Printf("Skipping local image %s of pod %s/%s", ref, pod.Namespace, pod.Name)
+				continue
+			}
+			image, err := f.resolveImage(ctx, pod, ref)
+			if err != nil {
+				return nil, fmt.Errorf("resolve image %s failed: %w", ref, err)
+			}
+			if image != ref {
+				log.Printf("Resolved image %s to %s for pod %s/%s", ref, image, pod.Namespace, pod.Name)
+			}
+			if _, ok := seen[image]; ok {
+				continue
+			}
+			seen[image] = struct{}{}
+			images[phase] = append(images[phase], image)
+		}
+	}
+	return images, nil
+}
+
+// sandboxImage returns the sandbox image of the pod runtime class when
+// CheckSandboxImage is set, or empty if there is none to check.
+func (f *ImageCompatibilityPlugin) sandboxImage(pod *v1.Pod) string {
+	if !f.args.CheckSandboxImage {
+		return ""
+	}
+	if image, ok := f.args.RuntimeClassSandboxImages[podRuntimeClass(pod)]; ok {
+		return image
+	}
+	return f.args.SandboxImage
+}
+
 // createNodeFeatureGroupsForPod creates temporary NodeFeatureGroup CRs for all
 // container images declared in the Pod spec. These CRs will be automatically
 // cleaned up when the Pod is deleted via OwnerReference TTL mechanism.
-func (f *ImageCompatibilityPlugin) createNodeFeatureGroupsForPod(ctx context.Context, pod *v1.Pod, namespace string) ([]string, error) {
-	var createdNFGs []string
-	for _, container := range pod.Spec.Containers {
-		nfgNames, err := f.createNodeFeatureGroupsForImage(ctx, pod, container.Image, namespace)
+// Images are processed concurrently, bounded by the scheduler's parallelism.
+// It returns the NFG names of each of the deduplicated images.
+func (f *ImageCompatibilityPlugin) createNodeFeatureGroupsForPod(ctx context.Context, pod *v1.Pod, images []string, namespace string) (map[string][]string, error) {
+	results := make([][]string, len(images))
+	errs := make([]error, len(images))
+	f.parallelizer.Until(ctx, len(images), func(i int) {
+		// Every image gets the same budget, so that a slow image cannot
+		// consume the time of the others
+		imageCtx := ctx
+		if timeout := f.args.PerImageTimeout.Duration; timeout > 0 {
+			var cancel context.CancelFunc
+			imageCtx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		nfgNames, err := f.createNodeFeatureGroupsForImage(imageCtx, pod, images[i], namespace)
+		if err != nil {
+			if ctx.Err() == nil && errors.Is(imageCtx.Err(), context.DeadlineExceeded) {
+				err = fmt.Errorf("per-image timeout of %v exceeded: %w", f.args.PerImageTimeout.Duration, err)
+			}
+			errs[i] = fmt.Errorf("create NodeFeatureGroups for image %s failed: %w", images[i], err)
+			return
+		}
+		results[i] = nfgNames
+	}, PluginName)
+
+	for _, err := range errs {
 		if err != nil {
-			return nil, fmt.Errorf("create NodeFeatureGroups for image %s failed: %w", container.Image, err)
+			return nil, err
 		}
-		createdNFGs = append(createdNFGs, nfgNames...)
 	}
-	return createdNFGs, nil
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("create NodeFeatureGroups for pod %s/%s interrupted: %w", pod.Namespace, pod.Name, err)
+	}
+
+	imageNFGs := make(map[string][]string, len(images))
+	for i, image := range images {
+		imageNFGs[image] = results[i]
+	}
+	return imageNFGs, nil
 }
 
 // updateCacheForImage updates the cache for a specific image with the given NFG names
+// An empty list records that the image has no compatibility requirements.
 func (f *ImageCompatibilityPlugin) updateCacheForImage(imageName string, nfgNames []string) {
-	if len(nfgNames) == 0 {
-		return
+	entry := nfgCacheEntry{nfgNames: nfgNames, noRequirements: len(nfgNames) == 0, cachedAt: time.Now()}
+	if ttl := f.resolveCacheTTL(imageName); ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
 	}
 
-	f.imageToNFGCacheMutex.Lock()
-	f.imageToNFGCache[imageName] = nfgNames
-	f.imageToNFGCacheMutex.Unlock()
+	f.imageToNFGCache.set(imageName, entry)
+	if entry.noRequirements {
+		log.Printf("Cached no compatibility requirements for image %s", imageName)
+		return
+	}
 	log.Printf("Cached NFGs %v for image %s", nfgNames, imageName)
 }
 
+// resolveCacheTTL returns how long NFGs for the image may be reused. Tags are
+// matched against CacheTTLOverrides in order, falling back to CacheTTL.
+// Digest-pinned images are immutable and get the longest configured TTL.
+func (f *ImageCompatibilityPlugin) resolveCacheTTL(imageName string) time.Duration {
+	ref, err := registry.ParseReference(imageName)
+	if err != nil {
+		return f.args.CacheTTL.Duration
+	}
+
+	if ref.ValidateReferenceAsDigest() == nil {
+		longest := f.args.CacheTTL.Duration
+		for _, o := range f.args.CacheTTLOverrides {
+			if longest == 0 || o.TTL.Duration == 0 {
+				// No expiry is the longest TTL possible
+				return 0
+			}
+			longest = max(longest, o.TTL.Duration)
+		}
+		return longest
+	}
+
+	tag := ref.ReferenceOrDefault()
+	for _, o := range f.args.CacheTTLOverrides {
+		if matched, _ := path.Match(o.TagPattern, tag); matched {
+			return o.TTL.Duration
+		}
+	}
+	return f.args.CacheTTL.Duration
+}
+
+// cacheEntries returns the cached image evaluations sorted by image, or
+// only the entry of image if it is not empty.
+func (f *ImageCompatibilityPlugin) cacheEntries(image string) []CacheEntry {
+	entries := []CacheEntry{}
+	for cachedImage, entry := range f.imageToNFGCache.snapshot() {
+		if image != "" && cachedImage != image {
+			continue
+		}
+		e := CacheEntry{
+			Image:             cachedImage,
+			NodeFeatureGroups: slices.Clone(entry.nfgNames),
+			NoRequirements:    entry.noRequirements,
+			CachedAt:          entry.cachedAt,
+		}
+		if !entry.expiresAt.IsZero() {
+			expiresAt := entry.expiresAt
+			e.ExpiresAt = &expiresAt
+		}
+		entries = append(entries, e)
+	}
+	slices.SortFunc(entries, func(a, b CacheEntry) int { return strings.Compare(a.Image, b.Image) })
+	return entries
+}
+
 // removeFromCache removes an image from the cache
 func (f *ImageCompatibilityPlugin) removeFromCache(imageName string) {
-	f.imageToNFGCacheMutex.Lock()
-	delete(f.imageToNFGCache, imageName)
-	f.imageToNFGCacheMutex.Unlock()
+	f.imageToNFGCache.delete(imageName)
 	log.Printf("Removed image %s from cache", imageName)
 }
 
 // getValidCachedNFGs returns valid NFGs from cache for a specific image
 func (f *ImageCompatibilityPlugin) getValidCachedNFGs(ctx context.Context, imageName, namespace string) ([]string, bool) {
-	f.imageToNFGCacheMutex.RLock()
-	cachedNFGs, found := f.imageToNFGCache[imageName]
-	f.imageToNFGCacheMutex.RUnlock()
+	entry, found := f.imageToNFGCache.get(imageName)
+
+	cachedNFGs := entry.nfgNames
+	if !found || (len(cachedNFGs) == 0 && !entry.noRequirements) {
+		return nil, false
+	}
+
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		log.Printf("Cached NFGs for image %s expired at %v, removing from cache", imageName, entry.expiresAt)
+		f.removeFromCache(imageName)
+		return nil, false
+	}
 
-	if !found || len(cachedNFGs) == 0 {
+	if entry.noRequirements {
+		return nil, true
+	}
+
+	nfdCli, err := f.getNfdClient()
+	if err != nil {
+		log.Printf("Cannot verify cached NFGs for image %s: %v", imageName, err)
 		return nil, false
 	}
 
 	// Verify all cached NFGs still exist
 	validNFGs := []string{}
 	for _, nfgName := range cachedNFGs {
-		_, err := f.nfdClient.NfdV1alpha1().NodeFeatureGroups(namespace).Get(ctx, nfgName, metav1.GetOptions{})
+		_, err := nfdCli.NfdV1alpha1().NodeFeatureGroups(namespace).Get(ctx, nfgName, metav1.GetOptions{})
 		if err == nil {
 			validNFGs = append(validNFGs, nfgName)
 		} else {
@@ -293,9 +1171,10 @@ func (f *ImageCompatibilityPlugin) getValidCachedNFGs(ctx context.Context, image
 		return nil, false
 	}
 
-	// Update cache with only valid NFGs if some were invalid
+	// Update cache with only valid NFGs if some were invalid, keeping the original expiry
 	if len(validNFGs) != len(cachedNFGs) {
-		f.updateCacheForImage(imageName, validNFGs)
+		entry.nfgNames = validNFGs
+		f.imageToNFGCache.set(imageName, entry)
 		log.Printf("Updated cache for image %s: removed %d invalid NFGs (original: %d, valid: %d)",
 			imageName, len(cachedNFGs)-len(validNFGs), len(cachedNFGs), len(validNFGs))
 	}
@@ -307,28 +1186,49 @@ func (f *ImageCompatibilityPlugin) getValidCachedNFGs(ctx context.Context, image
 // single image artifact with TTL via OwnerReference to the Pod.
 func (f *ImageCompatibilityPlugin) createNodeFeatureGroupsForImage(ctx context.Context, pod *v1.Pod, imageName, namespace string) ([]string, error) {
 	// Check cache first
-	if validNFGs, found := f.getValidCachedNFGs(ctx, imageName, namespace); found {
+	cacheKey := f.imageCacheKey(ctx, imageName)
+	previous, _ := f.imageToNFGCache.get(cacheKey)
+	if validNFGs, found := f.getValidCachedNFGs(ctx, cacheKey, namespace); found {
 		log.Printf("Reusing cached NFGs %v for image %s", validNFGs, imageName)
 		return validNFGs, nil
 	}
 
-	ref, err := registry.ParseReference(imageName)
+	nfdCli, err := f.getNfdClient()
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse image reference %s: %w", imageName, err)
+		return nil, err
 	}
 
-	ac := artifactcli.New(
-		&ref,
-		artifactcli.WithArgs(artifactcli.Args{PlainHttp: f.args.PlainHttp}),
-		artifactcli.WithAuthDefault(),
-	)
+	if f.args.UseExistingNodeFeatureGroups {
+		existing, err := existingNFGNames(ctx, nfdCli, namespace, imageName)
+		if err != nil {
+			return nil, err
+		}
+		if len(existing) > 0 {
+			log.Printf("Using existing NFGs %v for image %s", existing, imageName)
+			f.updateCacheForImage(cacheKey, existing)
+			return existing, nil
+		}
+	}
+
+	ac, err := newArtifactClient(ctx, f.handle.ClientSet(), imageName, f.args)
+	if err != nil {
+		return nil, err
+	}
 
-	mgmt := NewFeatureGroupManagement(ac)
-	nfgs, err := mgmt.CreateNodeFeatureGroupsFromArtifact(ctx, f.nfdClient, pod, namespace)
+	mgmt := NewFeatureGroupManagement(ac).
+		WithFetchOptions(artifactFetchOptions(f.args)).
+		WithCreateConcurrency(nfgCreateConcurrency(f.args)).
+		WithReusableGroups(reusableNFGs(ctx, nfdCli, namespace, previous.nfgNames))
+	nfgs, err := mgmt.CreateNodeFeatureGroupsFromArtifact(ctx, nfdCli, pod, namespace)
 	if err != nil {
+		if isImageNotFoundError(err) {
+			return nil, &ImageNotFoundError{Image: imageName, Err: err}
+		}
 		return nil, fmt.Errorf("failed to create NodeFeatureGroups from artifact for image %s: %w", imageName, err)
 	}
 
+	f.artifactHistory.record(imageName, nfgs)
+
 	// Extract NFG names and update cache
 	var nfgNames []string
 	for _, nfg := range nfgs {
@@ -336,11 +1236,110 @@ func (f *ImageCompatibilityPlugin) createNodeFeatureGroupsForImage(ctx context.C
 	}
 
 	// Update cache with all NFG names
-	f.updateCacheForImage(imageName, nfgNames)
+	f.updateCacheForImage(cacheKey, nfgNames)
 
 	return nfgNames, nil
 }
 
+// newArtifactClient creates the client fetching the compatibility artifact of
+// an image, authenticated with the pull secret configured for its registry.
+func newArtifactClient(ctx context.Context, kubeClient k8sclient.Interface, imageName string, args ImageCompatibilityPluginArgs) (artifactcli.ArtifactClient, error) {
+	return newInheritingArtifactClient(ctx, kubeClient, imageName, args, MaxArtifactInheritanceDepth)
+}
+
+// newInheritingArtifactClient creates the artifact client of the image, also
+// merging the compatibility specs of up to depth base images when
+// inheritance is enabled. Images of the offline artifact bundle are served
+// from it as-is, without contacting the registry.
+func newInheritingArtifactClient(ctx context.Context, kubeClient k8sclient.Interface, imageName string, args ImageCompatibilityPluginArgs, depth int) (artifactcli.ArtifactClient, error) {
+	if offline := lookupOfflineArtifact(ctx, kubeClient, imageName, args); offline != nil {
+		return offline, nil
+	}
+	client, err := newCachedImageArtifactClient(ctx, kubeClient, imageName, args)
+	if err != nil || !args.ArtifactInheritance || depth == 0 {
+		return client, err
+	}
+
+	ref, err := registry.ParseReference(imageName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image reference %s: %w", imageName, err)
+	}
+	cred, err := lookupRegistryCredential(ctx, kubeClient, ref.Registry, args)
+	if err != nil {
+		return nil, err
+	}
+	return &inheritingArtifactClient{
+		image: imageName,
+		own:   client,
+		baseImage: func(ctx context.Context) (string, error) {
+			return fetchBaseImageName(ctx, ref, newRegistryClient(ref.Registry, cred), args.PlainHttp)
+		},
+		baseClient: func(ctx context.Context, baseImage string) (artifactcli.ArtifactClient, error) {
+			return newInheritingArtifactClient(ctx, kubeClient, baseImage, args, depth-1)
+		},
+	}, nil
+}
+
+// newImageArtifactClient creates the artifact client of the image alone.
+func newImageArtifactClient(ctx context.Context, kubeClient k8sclient.Interface, imageName string, args ImageCompatibilityPluginArgs) (artifactcli.ArtifactClient, error) {
+	ref, err := registry.ParseReference(imageName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image reference %s: %w", imageName, err)
+	}
+
+	cred, err := lookupRegistryCredential(ctx, kubeClient, ref.Registry, args)
+	if err != nil {
+		return nil, err
+	}
+	client := newReferrersArtifactClient(ref, cred, args.PlainHttp)
+	if args.ArtifactReferenceTemplate == "" {
+		return client, nil
+	}
+
+	// The compatibility artifact is published at a reference derived from the
+	// image, fall back to the image referrers if it does not exist
+	derivedRef, err := deriveArtifactReference(args.ArtifactReferenceTemplate, ref)
+	if err != nil {
+		log.Printf("Failed to derive compatibility artifact reference of image %s, using the image referrers: %v", imageName, err)
+		return client, nil
+	}
+	derivedCred, err := lookupRegistryCredential(ctx, kubeClient, derivedRef.Registry, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fallbackArtifactClient{
+		derived:  newReferenceArtifactClient(derivedRef, derivedCred, args.PlainHttp),
+		fallback: client,
+		image:    imageName,
+	}, nil
+}
+
+// artifactFetchOptions returns the artifact fetch options configured by args,
+// falling back to the defaults for unset values.
+func artifactFetchOptions(args ImageCompatibilityPluginArgs) FetchOptions {
+	opts := FetchOptions{
+		Timeout:  args.ArtifactFetchTimeout.Duration,
+		Attempts: args.ArtifactFetchAttempts,
+		Backoff:  ArtifactFetchBackoff,
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultArtifactFetchTimeout
+	}
+	if opts.Attempts == 0 {
+		opts.Attempts = DefaultArtifactFetchAttempts
+	}
+	return opts
+}
+
+// nfgCreateConcurrency returns the configured NodeFeatureGroup creation
+// concurrency, falling back to the default.
+func nfgCreateConcurrency(args ImageCompatibilityPluginArgs) int {
+	if args.NFGCreateConcurrency == 0 {
+		return DefaultNFGCreateConcurrency
+	}
+	return args.NFGCreateConcurrency
+}
+
 // collectCompatibleNodesFromNFGs computes compatible nodes from specific NFGs with retry logic
 func (f *ImageCompatibilityPlugin) collectCompatibleNodesFromNFGs(ctx context.Context, namespace string, nfgNames []string) (map[string]struct{}, error) {
 	startTime := time.Now()
@@ -348,17 +1347,21 @@ func (f *ImageCompatibilityPlugin) collectCompatibleNodesFromNFGs(ctx context.Co
 	retryInterval := 500 * time.Millisecond
 
 	for attempt := 0; time.Since(startTime) < maxWait; attempt++ {
-		intersection := f.computeIntersection(ctx, namespace, nfgNames)
+		compatible := f.computeCompatibleNodes(ctx, namespace, nfgNames)
 
-		if len(intersection) > 0 {
-			log.Printf("Found %d compatible nodes after %v", len(intersection), time.Since(startTime))
-			return intersection, nil
+		if len(compatible) > 0 {
+			log.Printf("Found %d compatible nodes after %v", len(compatible), time.Since(startTime))
+			return compatible, nil
 		}
 
 		// Wait and retry
 		if time.Since(startTime) < maxWait {
 			log.Printf("No compatible nodes, waiting (attempt %d, elapsed: %v)", attempt+1, time.Since(startTime))
-			time.Sleep(retryInterval)
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("waiting for NodeFeatureGroup status interrupted: %w", ctx.Err())
+			case <-time.After(retryInterval):
+			}
 		}
 	}
 
@@ -366,49 +1369,84 @@ func (f *ImageCompatibilityPlugin) collectCompatibleNodesFromNFGs(ctx context.Co
 	return make(map[string]struct{}), nil
 }
 
-// computeIntersection computes intersection of nodes from all NFGs
-func (f *ImageCompatibilityPlugin) computeIntersection(ctx context.Context, namespace string, nfgNames []string) map[string]struct{} {
-	var intersection map[string]struct{}
-	first := true
+// computeCompatibleNodes computes the nodes listed in the NFGs that the
+// DecisionFunc accepts, by default those listed in at least MinMatchRatio of
+// the NFGs, which is the intersection of the nodes of all NFGs. NFGs without
+// nodes are ignored.
+func (f *ImageCompatibilityPlugin) computeCompatibleNodes(ctx context.Context, namespace string, nfgNames []string) map[string]struct{} {
+	var tags []string
+	var nodesBySet []map[string]struct{}
+	listed := make(map[string]struct{})
 
 	for _, nfgName := range nfgNames {
-		nodes, err := f.getNFGNodes(ctx, namespace, nfgName)
+		nfg, nodes, err := f.getNFGStatus(ctx, namespace, nfgName)
 		if err != nil || len(nodes) == 0 {
 			continue
 		}
 
-		if first {
-			intersection = nodes
-			first = false
-			continue
+		tags = append(tags, nfg.Annotations[NFGTagAnnotation])
+		nodesBySet = append(nodesBySet, nodes)
+		for node := range nodes {
+			listed[node] = struct{}{}
 		}
+	}
 
-		// Intersect with current nodes
-		for node := range intersection {
-			if _, ok := nodes[node]; !ok {
-				delete(intersection, node)
-			}
+	decide := f.decision()
+	compatible := make(map[string]struct{})
+	for node := range listed {
+		sets := make([]CompatibilitySetStatus, len(nodesBySet))
+		for i, nodes := range nodesBySet {
+			_, matched := nodes[node]
+			sets[i] = CompatibilitySetStatus{Tag: tags[i], Matched: matched}
+		}
+		if decide(node, sets) {
+			compatible[node] = struct{}{}
 		}
 	}
+	return compatible
+}
+
+// minMatchRatio returns the configured MinMatchRatio, 1 by default.
+func minMatchRatio(args ImageCompatibilityPluginArgs) float64 {
+	if args.MinMatchRatio == nil {
+		return 1
+	}
+	return *args.MinMatchRatio
+}
 
-	if intersection == nil {
-		return make(map[string]struct{})
+// meetsMatchRatio reports whether matched of total compatibility sets meet ratio.
+func meetsMatchRatio(matched, total int, ratio float64) bool {
+	if total == 0 {
+		return true
 	}
-	return intersection
+	// Tolerate floating point error, e.g. 4/5 against 0.8
+	return float64(matched)/float64(total) >= ratio-1e-9
 }
 
 // getNFGNodes retrieves nodes from a specific NFG
 func (f *ImageCompatibilityPlugin) getNFGNodes(ctx context.Context, namespace, nfgName string) (map[string]struct{}, error) {
-	nfg, err := f.nfdClient.NfdV1alpha1().NodeFeatureGroups(namespace).Get(ctx, nfgName, metav1.GetOptions{})
+	_, nodes, err := f.getNFGStatus(ctx, namespace, nfgName)
+	return nodes, err
+}
+
+// getNFGStatus retrieves a specific NFG and the nodes of its status. A missing
+// NFG is returned empty, without nodes.
+func (f *ImageCompatibilityPlugin) getNFGStatus(ctx context.Context, namespace, nfgName string) (*nfdv1alpha1.NodeFeatureGroup, map[string]struct{}, error) {
+	nfdCli, err := f.getNfdClient()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nfg, err := nfdCli.NfdV1alpha1().NodeFeatureGroups(namespace).Get(ctx, nfgName, metav1.GetOptions{})
 	if err != nil {
 		// NFG not found, return empty nodes map
 		log.Printf("NFG %s not found in namespace %s: %v", nfgName, namespace, err)
-		return make(map[string]struct{}), nil
+		return &nfdv1alpha1.NodeFeatureGroup{}, make(map[string]struct{}), nil
 	}
 
 	// Check if NFG has status field
 	if nfg.Status.Nodes == nil {
-		return nil, fmt.Errorf("NFG %s has empty status.nodes, please check the NFD configuration", nfgName)
+		return nil, nil, fmt.Errorf("NFG %s has empty status.nodes, please check the NFD configuration", nfgName)
 	}
 
 	nodes := make(map[string]struct{})
@@ -417,7 +1455,7 @@ func (f *ImageCompatibilityPlugin) getNFGNodes(ctx context.Context, namespace, n
 	}
 
 	log.Printf("NFG %s has %d compatible nodes", nfgName, len(nodes))
-	return nodes, nil
+	return nfg, nodes, nil
 }
 
 // cleanupOrphanedNFGs periodically cleans up NFGs whose associated Pod no longer exists
@@ -432,10 +1470,21 @@ func (f *ImageCompatibilityPlugin) startNFGCleanup(ctx context.Context) {
 			return
 		case <-ticker.C:
 			f.cleanupOrphanedNFGs(ctx)
+			f.pruneRequiredNFGs()
 		}
 	}
 }
 
+// pruneRequiredNFGs forgets the required NodeFeatureGroups of the pods that
+// were deleted or bound.
+func (f *ImageCompatibilityPlugin) pruneRequiredNFGs() {
+	podLister := f.handle.SharedInformerFactory().Core().V1().Pods().Lister()
+	f.requiredNFGs.prune(func(namespace, name string, uid types.UID) bool {
+		pod, err := podLister.Pods(namespace).Get(name)
+		return err == nil && pod.UID == uid && pod.Spec.NodeName == ""
+	})
+}
+
 // cleanupOrphanedNFGs finds and deletes NFGs whose associated Pods no longer exist
 func (f *ImageCompatibilityPlugin) cleanupOrphanedNFGs(ctx context.Context) {
 	namespace, err := f.getNfdMasterNamespace(ctx)
@@ -444,8 +1493,19 @@ func (f *ImageCompatibilityPlugin) cleanupOrphanedNFGs(ctx context.Context) {
 		return
 	}
 
+	nfdCli, err := f.getNfdClient()
+	if err != nil {
+		log.Printf("Cannot cleanup NFGs: %v", err)
+		return
+	}
+	f.deleteOrphanedNFGs(ctx, f.handle.ClientSet(), nfdCli, namespace)
+}
+
+// deleteOrphanedNFGs deletes the NFGs in namespace whose pod no longer exists.
+// Reused NFGs are relabeled with the pod they were last used for.
+func (f *ImageCompatibilityPlugin) deleteOrphanedNFGs(ctx context.Context, kubeClient k8sclient.Interface, nfdCli nfdclientset.Interface, namespace string) {
 	// List all NFGs with managed-by=ImageCompatibilityFilter label
-	nfgs, err := f.nfdClient.NfdV1alpha1().NodeFeatureGroups(namespace).List(ctx, metav1.ListOptions{
+	nfgs, err := nfdCli.NfdV1alpha1().NodeFeatureGroups(namespace).List(ctx, metav1.ListOptions{
 		LabelSelector: "managed-by=ImageCompatibilityFilter",
 	})
 	if err != nil {
@@ -463,11 +1523,11 @@ func (f *ImageCompatibilityPlugin) cleanupOrphanedNFGs(ctx context.Context) {
 		}
 
 		// Check if Pod still exists
-		_, err := f.handle.ClientSet().CoreV1().Pods(podNamespace).Get(ctx, podName, metav1.GetOptions{})
+		_, err := kubeClient.CoreV1().Pods(podNamespace).Get(ctx, podName, metav1.GetOptions{})
 		if err != nil {
 			// Pod not found or error - delete the NFG
 			log.Printf("Deleting orphaned NFG %s (Pod %s/%s not found)", nfg.Name, podNamespace, podName)
-			deleteErr := f.nfdClient.NfdV1alpha1().NodeFeatureGroups(namespace).Delete(ctx, nfg.Name, metav1.DeleteOptions{})
+			deleteErr := nfdCli.NfdV1alpha1().NodeFeatureGroups(namespace).Delete(ctx, nfg.Name, metav1.DeleteOptions{})
 			if deleteErr != nil {
 				log.Printf("Failed to delete NFG %s: %v (NFG namespace: %s)", nfg.Name, deleteErr, namespace)
 			} else {
@@ -481,19 +1541,18 @@ func (f *ImageCompatibilityPlugin) cleanupOrphanedNFGs(ctx context.Context) {
 
 // removeFromCacheByNFGName removes an NFG from all cache entries
 func (f *ImageCompatibilityPlugin) removeFromCacheByNFGName(nfgName string) {
-	f.imageToNFGCacheMutex.Lock()
-	defer f.imageToNFGCacheMutex.Unlock()
-
-	for image, nfgs := range f.imageToNFGCache {
+	f.imageToNFGCache.update(func(image string, entry nfgCacheEntry) (nfgCacheEntry, bool) {
 		newNFGs := []string{}
-		for _, nfg := range nfgs {
+		for _, nfg := range entry.nfgNames {
 			if nfg != nfgName {
 				newNFGs = append(newNFGs, nfg)
 			}
 		}
-		if len(newNFGs) != len(nfgs) {
-			f.imageToNFGCache[image] = newNFGs
-			log.Printf("Removed NFG %s from cache for image %s", nfgName, image)
+		if len(newNFGs) == len(entry.nfgNames) {
+			return entry, false
 		}
-	}
+		entry.nfgNames = newNFGs
+		log.Printf("Removed NFG %s from cache for image %s", nfgName, image)
+		return entry, true
+	})
 }
@@ -0,0 +1,523 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/klog/v2"
+	fwk "k8s.io/kube-scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/registry/remote/errcode"
+	nfdfake "sigs.k8s.io/node-feature-discovery/api/generated/clientset/versioned/fake"
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
+)
+
+func TestResolveCacheTTL(t *testing.T) {
+	plugin := &ImageCompatibilityPlugin{
+		args: ImageCompatibilityPluginArgs{
+			CacheTTL: metav1.Duration{Duration: time.Hour},
+			CacheTTLOverrides: []CacheTTLOverride{
+				{TagPattern: "nightly", TTL: metav1.Duration{Duration: time.Minute}},
+				{TagPattern: "dev*", TTL: metav1.Duration{Duration: 5 * time.Minute}},
+				{TagPattern: "v*.*.*", TTL: metav1.Duration{Duration: 24 * time.Hour}},
+			},
+		},
+	}
+
+	tests := []struct {
+		image string
+		want  time.Duration
+	}{
+		{image: "docker.io/library/app:nightly", want: time.Minute},
+		{image: "docker.io/library/app:dev-123", want: 5 * time.Minute},
+		{image: "docker.io/library/app:v1.2.3", want: 24 * time.Hour},
+		{image: "docker.io/library/app:stable", want: time.Hour},
+		{image: "docker.io/library/app", want: time.Hour},
+		{image: "docker.io/library/app@sha256:9b0b0ec2b1c3f08d1a13a3a3b3e2a8d8d8b8f3c5e9d4a7b3e1f2c3d4e5f6a7b8", want: 24 * time.Hour},
+		{image: "not a reference", want: time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := plugin.resolveCacheTTL(tt.image); got != tt.want {
+			t.Errorf("resolveCacheTTL(%q) = %v, want %v", tt.image, got, tt.want)
+		}
+	}
+}
+
+func TestResolveCacheTTL_DigestWithoutExpiry(t *testing.T) {
+	plugin := &ImageCompatibilityPlugin{
+		args: ImageCompatibilityPluginArgs{
+			CacheTTLOverrides: []CacheTTLOverride{
+				{TagPattern: "nightly", TTL: metav1.Duration{Duration: time.Minute}},
+			},
+		},
+	}
+
+	image := "docker.io/library/app@sha256:9b0b0ec2b1c3f08d1a13a3a3b3e2a8d8d8b8f3c5e9d4a7b3e1f2c3d4e5f6a7b8"
+	if got := plugin.resolveCacheTTL(image); got != 0 {
+		t.Errorf("expected digest-pinned image to never expire, got %v", got)
+	}
+}
+
+func TestCacheNoRequirements(t *testing.T) {
+	plugin := &ImageCompatibilityPlugin{imageToNFGCache: newNFGCache()}
+
+	image := "docker.io/library/plain:v1"
+	plugin.updateCacheForImage(image, nil)
+
+	nfgNames, found := plugin.getValidCachedNFGs(context.Background(), image, "nfd")
+	if !found || len(nfgNames) != 0 {
+		t.Errorf("expected cached no requirements for %s, got %v, %v", image, nfgNames, found)
+	}
+	if _, found := plugin.getValidCachedNFGs(context.Background(), "docker.io/library/other:v1", "nfd"); found {
+		t.Errorf("expected uncached image not to be found")
+	}
+}
+
+func TestPrefixImageResolver(t *testing.T) {
+	resolver := newPrefixImageResolver([]ImageRewrite{
+		{From: "docker.io/", To: "mirror.example.com/docker.io/"},
+		{From: "quay.io/team/app:", To: "quay.io/team/app:pinned-"},
+	})
+
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: "docker.io/library/nginx:1.25", want: "mirror.example.com/docker.io/library/nginx:1.25"},
+		{image: "quay.io/team/app:v1", want: "quay.io/team/app:pinned-v1"},
+		{image: "registry.k8s.io/pause:3.9", want: "registry.k8s.io/pause:3.9"},
+	}
+
+	for _, tt := range tests {
+		got, err := resolver(context.Background(), &v1.Pod{}, tt.image)
+		if err != nil {
+			t.Fatalf("unexpected error resolving %q: %v", tt.image, err)
+		}
+		if got != tt.want {
+			t.Errorf("resolver(%q) = %q, want %q", tt.image, got, tt.want)
+		}
+	}
+}
+
+func TestStatusFromError(t *testing.T) {
+	nfgResource := schema.GroupResource{Group: "nfd.k8s-sigs.io", Resource: "nodefeaturegroups"}
+
+	tests := []struct {
+		name string
+		err  error
+		want fwk.Code
+	}{
+		{name: "too many requests", err: apierrors.NewTooManyRequests("throttled", 1), want: fwk.Error},
+		{name: "service unavailable", err: apierrors.NewServiceUnavailable("down"), want: fwk.Error},
+		{name: "wrapped timeout", err: fmt.Errorf("create failed: %w", apierrors.NewServerTimeout(nfgResource, "create", 1)), want: fwk.Error},
+		{name: "deadline exceeded", err: fmt.Errorf("fetch: %w", context.DeadlineExceeded), want: fwk.Error},
+		{name: "forbidden", err: apierrors.NewForbidden(nfgResource, "nfg", errors.New("denied")), want: fwk.Error},
+		{name: "plain error", err: errors.New("bad artifact"), want: fwk.Error},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statusFromError("prefilter failed", tt.err).Code(); got != tt.want {
+				t.Errorf("statusFromError() code = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsSchedulableAfterNodeChange(t *testing.T) {
+	plugin := &ImageCompatibilityPlugin{}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}}
+	node := func(labels map[string]string) *v1.Node {
+		return &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", Labels: labels}}
+	}
+
+	tests := []struct {
+		name   string
+		oldObj interface{}
+		newObj interface{}
+		want   fwk.QueueingHint
+	}{
+		{
+			name:   "node added",
+			newObj: node(nil),
+			want:   fwk.Queue,
+		},
+		{
+			name:   "feature label changed",
+			oldObj: node(map[string]string{"feature.node.kubernetes.io/cpu-model.vendor_id": "AMD"}),
+			newObj: node(map[string]string{"feature.node.kubernetes.io/cpu-model.vendor_id": "Intel"}),
+			want:   fwk.Queue,
+		},
+		{
+			name:   "sub-namespace feature label added",
+			oldObj: node(nil),
+			newObj: node(map[string]string{"vendor.feature.node.kubernetes.io/gpu": "true"}),
+			want:   fwk.Queue,
+		},
+		{
+			name:   "unrelated label changed",
+			oldObj: node(map[string]string{"team": "a"}),
+			newObj: node(map[string]string{"team": "b"}),
+			want:   fwk.QueueSkip,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := plugin.isSchedulableAfterNodeChange(klog.Background(), pod, tt.oldObj, tt.newObj)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("hint = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsSchedulableAfterNodeFeatureGroupChange(t *testing.T) {
+	plugin := &ImageCompatibilityPlugin{requiredNFGs: newRequiredNFGIndex()}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default", UID: "pod-uid"}}
+	plugin.requiredNFGs.record(pod, []string{"nfg"})
+	nfgNamed := func(name, managedBy string, nodes ...string) *unstructured.Unstructured {
+		obj := &nfdv1alpha1.NodeFeatureGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"managed-by": managedBy}},
+		}
+		for _, n := range nodes {
+			obj.Status.Nodes = append(obj.Status.Nodes, nfdv1alpha1.FeatureGroupNode{Name: n})
+		}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			t.Fatalf("failed to convert NodeFeatureGroup: %v", err)
+		}
+		return &unstructured.Unstructured{Object: content}
+	}
+	nfg := func(managedBy string, nodes ...string) *unstructured.Unstructured {
+		return nfgNamed("nfg", managedBy, nodes...)
+	}
+
+	tests := []struct {
+		name   string
+		oldObj interface{}
+		newObj interface{}
+		want   fwk.QueueingHint
+	}{
+		{
+			name:   "status nodes added",
+			oldObj: nfg(PluginName, "node-a"),
+			newObj: nfg(PluginName, "node-a", "node-b"),
+			want:   fwk.Queue,
+		},
+		{
+			name:   "status nodes removed",
+			oldObj: nfg(PluginName, "node-a", "node-b"),
+			newObj: nfg(PluginName, "node-a"),
+			want:   fwk.QueueSkip,
+		},
+		{
+			name:   "not required by the pod",
+			oldObj: nfgNamed("other", PluginName),
+			newObj: nfgNamed("other", PluginName, "node-a"),
+			want:   fwk.QueueSkip,
+		},
+		{
+			name:   "status nodes unchanged",
+			oldObj: nfg(PluginName, "node-a"),
+			newObj: nfg(PluginName, "node-a"),
+			want:   fwk.QueueSkip,
+		},
+		{
+			name:   "not managed by plugin",
+			oldObj: nfg("someone-else"),
+			newObj: nfg("someone-else", "node-a"),
+			want:   fwk.QueueSkip,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := plugin.isSchedulableAfterNodeFeatureGroupChange(klog.Background(), pod, tt.oldObj, tt.newObj)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("hint = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsImageNotFoundError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "oras not found",
+			err:  fmt.Errorf("failed to transfer from artifact: %w", fmt.Errorf("docker.io/library/missing:v1: %w", errdef.ErrNotFound)),
+			want: true,
+		},
+		{
+			name: "repository unknown",
+			err: &errcode.ErrorResponse{
+				StatusCode: http.StatusNotFound,
+				Errors:     errcode.Errors{{Code: errcode.ErrorCodeNameUnknown, Message: "repository name not known to registry"}},
+			},
+			want: true,
+		},
+		{
+			name: "manifest unknown message",
+			err:  errors.New("GET https://registry/v2/app/manifests/v1: MANIFEST_UNKNOWN: manifest unknown"),
+			want: true,
+		},
+		{
+			name: "unauthorized",
+			err:  &errcode.ErrorResponse{StatusCode: http.StatusUnauthorized},
+			want: false,
+		},
+		{
+			name: "missing compatibility artifact",
+			err:  errors.New("compatibility artifact not found"),
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isImageNotFoundError(tt.err); got != tt.want {
+				t.Errorf("isImageNotFoundError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageNotFoundStatus(t *testing.T) {
+	err := &ImageNotFoundError{Image: "docker.io/library/missing:v1", Err: errdef.ErrNotFound}
+
+	plugin := &ImageCompatibilityPlugin{}
+	if got := plugin.imageNotFoundStatus(err).Code(); got != fwk.UnschedulableAndUnresolvable {
+		t.Errorf("expected UnschedulableAndUnresolvable, got %v", got)
+	}
+
+	plugin.args.RetryMissingImages = true
+	if got := plugin.imageNotFoundStatus(err).Code(); got != fwk.Unschedulable {
+		t.Errorf("expected Unschedulable with retryMissingImages, got %v", got)
+	}
+}
+
+func TestGetNfdClientUnavailable(t *testing.T) {
+	// Outside of a cluster without a kubeconfig no config can be loaded, so
+	// the client stays unavailable and an error is returned instead of nil.
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	t.Setenv("KUBERNETES_SERVICE_PORT", "")
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "missing"))
+	t.Setenv("HOME", t.TempDir())
+
+	plugin := &ImageCompatibilityPlugin{}
+	cli, err := plugin.getNfdClient()
+	if err == nil {
+		t.Fatalf("expected error, got client %v", cli)
+	}
+	if plugin.nfdClient != nil {
+		t.Errorf("expected nfd client to stay unset")
+	}
+}
+
+func TestNewRestConfigFromKubeconfig(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	t.Setenv("KUBERNETES_SERVICE_PORT", "")
+
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	content := `apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: https://test.example.com:6443
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+current-context: test
+users:
+- name: test
+  user:
+    token: secret
+`
+	if err := os.WriteFile(kubeconfig, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write kubeconfig: %v", err)
+	}
+
+	// Out of cluster, $KUBECONFIG is used when no path is given
+	t.Setenv("KUBECONFIG", kubeconfig)
+	for _, path := range []string{"", kubeconfig} {
+		restCfg, err := NewRestConfig(path)
+		if err != nil {
+			t.Fatalf("NewRestConfig(%q) failed: %v", path, err)
+		}
+		if restCfg.Host != "https://test.example.com:6443" {
+			t.Errorf("NewRestConfig(%q) host = %s", path, restCfg.Host)
+		}
+	}
+}
+
+func TestCollectCompatibleNodesFromNFGs_RespectsDeadline(t *testing.T) {
+	plugin := &ImageCompatibilityPlugin{nfdClient: nfdfake.NewSimpleClientset()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := plugin.collectCompatibleNodesFromNFGs(ctx, "nfd", []string{"missing"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= NfdUpdateGracePeriod {
+		t.Errorf("expected waiting to stop at the deadline, took %v", elapsed)
+	}
+}
+
+func TestPreFilterSkipsTerminatingPod(t *testing.T) {
+	plugin := &ImageCompatibilityPlugin{}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p", DeletionTimestamp: &metav1.Time{Time: time.Now()}}}
+
+	_, status := plugin.PreFilter(context.Background(), framework.NewCycleState(), pod, nil)
+	if status.Code() != fwk.Skip {
+		t.Errorf("expected Skip for terminating pod, got %v", status.Code())
+	}
+}
+
+func TestPreFilterSkipsGatedPod(t *testing.T) {
+	// No NFD client is set, so evaluating the pod would fail
+	plugin := &ImageCompatibilityPlugin{imageResolver: newPrefixImageResolver(nil)}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "p"},
+		Spec: v1.PodSpec{
+			SchedulingGates: []v1.PodSchedulingGate{{Name: "example.com/quota"}},
+			Containers:      []v1.Container{{Image: "app:v1"}},
+		},
+	}
+
+	_, status := plugin.PreFilter(context.Background(), framework.NewCycleState(), pod, nil)
+	if status.Code() != fwk.Skip {
+		t.Errorf("expected Skip for gated pod, got %v", status.Code())
+	}
+}
+
+func TestFilterGuards(t *testing.T) {
+	plugin := &ImageCompatibilityPlugin{}
+	newNodeInfo := func(unschedulable bool) fwk.NodeInfo {
+		ni := framework.NewNodeInfo()
+		ni.SetNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}, Spec: v1.NodeSpec{Unschedulable: unschedulable}})
+		return ni
+	}
+	compatible := framework.NewCycleState()
+	compatible.Write(PluginName, &CompatibilityState{CompatibleNodes: map[string]struct{}{"node1": {}}})
+
+	terminating := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p", DeletionTimestamp: &metav1.Time{Time: time.Now()}}}
+	// No cycle state is written for a pod skipped in PreFilter
+	if status := plugin.Filter(context.Background(), framework.NewCycleState(), terminating, newNodeInfo(false)); !status.IsSuccess() {
+		t.Errorf("expected Success for terminating pod, got %v", status.Code())
+	}
+
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p"}}
+	if status := plugin.Filter(context.Background(), compatible, pod, newNodeInfo(true)); status.Code() != fwk.Unschedulable {
+		t.Errorf("expected Unschedulable for cordoned node, got %v", status.Code())
+	}
+	if status := plugin.Filter(context.Background(), compatible, pod, newNodeInfo(false)); !status.IsSuccess() {
+		t.Errorf("expected Success for compatible node, got %v", status.Code())
+	}
+	tolerating := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p"}, Spec: v1.PodSpec{Tolerations: []v1.Toleration{{
+		Key:      v1.TaintNodeUnschedulable,
+		Operator: v1.TolerationOpExists,
+		Effect:   v1.TaintEffectNoSchedule,
+	}}}}
+	if status := plugin.Filter(context.Background(), compatible, tolerating, newNodeInfo(true)); !status.IsSuccess() {
+		t.Errorf("expected Success for cordoned node tolerated by the pod, got %v", status.Code())
+	}
+	// A node deleted mid-cycle leaves a NodeInfo without node
+	if status := plugin.Filter(context.Background(), compatible, pod, framework.NewNodeInfo()); status.Code() != fwk.Unschedulable {
+		t.Errorf("expected Unschedulable for deleted node, got %v", status.Code())
+	}
+}
+
+func TestCheckNfdAPIAvailable(t *testing.T) {
+	groupVersion := nfdv1alpha1.SchemeGroupVersion.String()
+	tests := []struct {
+		name      string
+		resources []*metav1.APIResourceList
+		err       error
+		wantErr   bool
+	}{
+		{
+			name:      "resource served",
+			resources: []*metav1.APIResourceList{{GroupVersion: groupVersion, APIResources: []metav1.APIResource{{Name: NodeFeatureGroupResource}}}},
+		},
+		{
+			name:    "group missing",
+			wantErr: true,
+		},
+		{
+			name:      "resource missing",
+			resources: []*metav1.APIResourceList{{GroupVersion: groupVersion, APIResources: []metav1.APIResource{{Name: "nodefeatures"}}}},
+			wantErr:   true,
+		},
+		{
+			name: "transient error",
+			err:  apierrors.NewServiceUnavailable("etcd leader changed"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &k8stesting.Fake{Resources: tt.resources}
+			if tt.err != nil {
+				fake.AddReactor("get", "resource", func(k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, tt.err
+				})
+			}
+			err := checkNfdAPIAvailable(&discoveryfake.FakeDiscovery{Fake: fake})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkNfdAPIAvailable() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWorksWithoutNodeFeatureGroups(t *testing.T) {
+	tests := []struct {
+		name string
+		args ImageCompatibilityPluginArgs
+		want bool
+	}{
+		{name: "NodeFeatureGroups only", want: false},
+		{name: "instance types", args: ImageCompatibilityPluginArgs{InstanceTypeFeaturesConfigMap: "kube-system/instance-types"}, want: true},
+		{name: "custom features", args: ImageCompatibilityPluginArgs{CustomFeaturesConfigMap: "kube-system/custom-features"}, want: true},
+		{name: "offline bundle", args: ImageCompatibilityPluginArgs{OfflineArtifactConfigMap: "kube-system/artifacts"}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := worksWithoutNodeFeatureGroups(tt.args); got != tt.want {
+				t.Errorf("worksWithoutNodeFeatureGroups() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
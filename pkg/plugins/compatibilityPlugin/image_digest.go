@@ -0,0 +1,112 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	k8sclient "k8s.io/client-go/kubernetes"
+	"oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+// digestIndex maps image tags to the digest reference they resolved to.
+type digestIndex struct {
+	mu      sync.Mutex
+	digests map[string]digestIndexEntry
+}
+
+// digestIndexEntry holds the digest reference of a tag and when the tag must
+// be resolved again.
+type digestIndexEntry struct {
+	digestRef string
+	expiresAt time.Time // Zero means the entry never expires
+}
+
+func newDigestIndex() *digestIndex {
+	return &digestIndex{digests: make(map[string]digestIndexEntry)}
+}
+
+// get returns the digest reference of the image tag, if it has not expired.
+func (d *digestIndex) get(image string) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	entry, found := d.digests[image]
+	if !found {
+		return "", false
+	}
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		delete(d.digests, image)
+		return "", false
+	}
+	return entry.digestRef, true
+}
+
+// set records the digest reference of the image tag for ttl, zero meaning
+// no expiry.
+func (d *digestIndex) set(image, digestRef string, ttl time.Duration) {
+	entry := digestIndexEntry{digestRef: digestRef}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.digests[image] = entry
+}
+
+// imageCacheKey returns the key of the image in the NFG cache. With
+// DedupeByDigest it is the digest reference the tag resolves to, so that tags
+// of the same image share a cache entry, otherwise the image itself. The image
+// is used when its digest cannot be resolved.
+func (f *ImageCompatibilityPlugin) imageCacheKey(ctx context.Context, imageName string) string {
+	if !f.args.DedupeByDigest {
+		return imageName
+	}
+	if digestRef, found := f.tagDigests.get(imageName); found {
+		return digestRef
+	}
+
+	digestRef, err := f.digestResolver(ctx, imageName)
+	if err != nil {
+		log.Printf("Failed to resolve the digest of image %s, caching it by tag: %v", imageName, err)
+		return imageName
+	}
+	if digestRef != imageName {
+		f.tagDigests.set(imageName, digestRef, f.resolveCacheTTL(imageName))
+		log.Printf("Resolved image %s to %s", imageName, digestRef)
+	}
+	return digestRef
+}
+
+// resolveImageDigest returns the digest reference of the image manifest,
+// e.g. docker.io/library/app@sha256:... for docker.io/library/app:v1.
+// Digest references are returned as-is.
+func resolveImageDigest(ctx context.Context, kubeClient k8sclient.Interface, imageName string, args ImageCompatibilityPluginArgs) (string, error) {
+	ref, err := registry.ParseReference(imageName)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse image reference %s: %w", imageName, err)
+	}
+	if ref.ValidateReferenceAsDigest() == nil {
+		return imageName, nil
+	}
+
+	cred, err := lookupRegistryCredential(ctx, kubeClient, ref.Registry, args)
+	if err != nil {
+		return "", err
+	}
+	repo, err := remote.NewRepository(ref.String())
+	if err != nil {
+		return "", err
+	}
+	repo.Client = newRegistryClient(ref.Registry, cred)
+	repo.PlainHTTP = args.PlainHttp
+
+	desc, err := repo.Resolve(ctx, ref.ReferenceOrDefault())
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve image %s: %w", imageName, err)
+	}
+	ref.Reference = desc.Digest.String()
+	return ref.String(), nil
+}
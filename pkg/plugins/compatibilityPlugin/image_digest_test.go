@@ -0,0 +1,36 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"testing"
+)
+
+func TestImageCacheKeyDedupesTagsByDigest(t *testing.T) {
+	digestRef := "docker.io/library/app@sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	resolved := 0
+	plugin := &ImageCompatibilityPlugin{
+		args:            ImageCompatibilityPluginArgs{DedupeByDigest: true},
+		imageToNFGCache: newNFGCache(),
+		tagDigests:      newDigestIndex(),
+		digestResolver: func(context.Context, string) (string, error) {
+			resolved++
+			return digestRef, nil
+		},
+	}
+	ctx := context.Background()
+
+	plugin.updateCacheForImage(plugin.imageCacheKey(ctx, "docker.io/library/app:v1"), nil)
+
+	key := plugin.imageCacheKey(ctx, "docker.io/library/app:stable")
+	if key != digestRef {
+		t.Fatalf("expected cache key %s, got %s", digestRef, key)
+	}
+	if _, found := plugin.getValidCachedNFGs(ctx, key, "nfd"); !found {
+		t.Errorf("expected the second tag to reuse the evaluation of the digest")
+	}
+
+	plugin.imageCacheKey(ctx, "docker.io/library/app:v1")
+	if resolved != 2 {
+		t.Errorf("expected each tag to be resolved once, got %d resolutions", resolved)
+	}
+}
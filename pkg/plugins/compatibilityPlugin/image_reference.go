@@ -0,0 +1,58 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"strings"
+
+	"github.com/distribution/reference"
+	v1 "k8s.io/api/core/v1"
+)
+
+// normalizeImageReference expands an image reference to its canonical form,
+// adding the docker.io registry, the library/ namespace of official images
+// and the latest tag, so that nginx, nginx:latest and
+// docker.io/library/nginx:latest all name the same image.
+func normalizeImageReference(image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", err
+	}
+	return reference.TagNameOnly(named).String(), nil
+}
+
+// isLocalImage reports whether the image reference as declared in the pod
+// spec has no registry host, like "app:v1" or "org/app:v1", or starts with
+// one of the prefixes. As in docker, the first path component is a registry
+// host when it contains a "." or ":" or is "localhost".
+func isLocalImage(image string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(image, prefix) {
+			return true
+		}
+	}
+	host, _, found := strings.Cut(image, "/")
+	if !found {
+		return true
+	}
+	return !strings.ContainsAny(host, ".:") && host != "localhost"
+}
+
+// resolveImage returns the effective image reference of a container image.
+// With NormalizeImageReferences, the image is normalized both before the
+// ImageResolver, so that rewrites match the canonical form, and after it,
+// so that cache keys and artifact references are canonical.
+func (f *ImageCompatibilityPlugin) resolveImage(ctx context.Context, pod *v1.Pod, image string) (string, error) {
+	if !f.args.NormalizeImageReferences {
+		return f.imageResolver(ctx, pod, image)
+	}
+
+	normalized, err := normalizeImageReference(image)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := f.imageResolver(ctx, pod, normalized)
+	if err != nil {
+		return "", err
+	}
+	return normalizeImageReference(resolved)
+}
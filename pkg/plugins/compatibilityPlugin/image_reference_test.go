@@ -0,0 +1,71 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNormalizeImageReference(t *testing.T) {
+	digest := "sha256:" + "a3ed95caeb02ffe68cdd9fd84406680ae93d633cb16422d00e8a7c22955b46d4"
+	tests := map[string]string{
+		"nginx":                              "docker.io/library/nginx:latest",
+		"nginx:latest":                       "docker.io/library/nginx:latest",
+		"docker.io/library/nginx":            "docker.io/library/nginx:latest",
+		"bitnami/redis:7":                    "docker.io/bitnami/redis:7",
+		"quay.io/org/app":                    "quay.io/org/app:latest",
+		"localhost:5000/app:v1":              "localhost:5000/app:v1",
+		"registry.example.com/app@" + digest: "registry.example.com/app@" + digest,
+	}
+	for image, want := range tests {
+		got, err := normalizeImageReference(image)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", image, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s: expected %s, got %s", image, want, got)
+		}
+	}
+
+	if _, err := normalizeImageReference("Invalid/Image"); err == nil {
+		t.Errorf("expected error for invalid reference")
+	}
+}
+
+func TestResolveImageNormalized(t *testing.T) {
+	plugin := &ImageCompatibilityPlugin{
+		args: ImageCompatibilityPluginArgs{NormalizeImageReferences: true},
+		imageResolver: newPrefixImageResolver([]ImageRewrite{
+			{From: "docker.io/library/", To: "mirror.example.com/library/"},
+		}),
+	}
+	for _, image := range []string{"nginx", "nginx:latest", "docker.io/library/nginx"} {
+		got, err := plugin.resolveImage(context.Background(), nil, image)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != "mirror.example.com/library/nginx:latest" {
+			t.Errorf("%s: expected the rewritten canonical reference, got %s", image, got)
+		}
+	}
+}
+
+func TestIsLocalImage(t *testing.T) {
+	prefixes := []string{"registry.local/imported/"}
+	tests := map[string]bool{
+		"app:v1":                          true,
+		"org/app:v1":                      true,
+		"nginx":                           true,
+		"registry.local/imported/app:v1":  true,
+		"registry.local/team/app:v1":      false,
+		"docker.io/library/nginx":         false,
+		"localhost/app:v1":                false,
+		"localhost:5000/app:v1":           false,
+		"registry.example.com:443/app:v1": false,
+	}
+	for image, want := range tests {
+		if got := isLocalImage(image, prefixes); got != want {
+			t.Errorf("%s: expected local %v, got %v", image, want, got)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"reflect"
+
+	oras "oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote"
+	compatv1alpha1 "sigs.k8s.io/node-feature-discovery/api/image-compatibility/v1alpha1"
+	artifactcli "sigs.k8s.io/node-feature-discovery/pkg/client-nfd/compat/artifact-client"
+)
+
+const (
+	// BaseImageNameAnnotation is the OCI image manifest annotation naming the
+	// image an image was built from.
+	BaseImageNameAnnotation = "org.opencontainers.image.base.name"
+	// MaxArtifactInheritanceDepth bounds the chain of base images walked.
+	MaxArtifactInheritanceDepth = 5
+)
+
+// inheritingArtifactClient merges the compatibility spec of an image with the
+// specs of its base images, so that inherited requirements are enforced.
+type inheritingArtifactClient struct {
+	image string
+	own   artifactcli.ArtifactClient
+	// baseImage returns the base image of image, empty when there is none
+	baseImage func(ctx context.Context) (string, error)
+	// baseClient returns the artifact client of a base image
+	baseClient func(ctx context.Context, baseImage string) (artifactcli.ArtifactClient, error)
+}
+
+// FetchCompatibilitySpec returns the union of the compatibility sets of the
+// image and its base image. Failing to resolve the base image or to fetch its
+// artifact, e.g. when it was deleted or needs a pull secret, is logged and
+// leaves the image with its own requirements: only errors of the image's own
+// artifact are returned.
+func (c *inheritingArtifactClient) FetchCompatibilitySpec(ctx context.Context) (*compatv1alpha1.Spec, error) {
+	spec, err := c.own.FetchCompatibilitySpec(ctx)
+	if err != nil && !isNoCompatibilityArtifactError(err) {
+		return nil, err
+	}
+	ownErr := err
+
+	baseImage, err := c.baseImage(ctx)
+	if err != nil {
+		log.Printf("Failed to get the base image of %s, not inheriting requirements: %v", c.image, err)
+		return spec, ownErr
+	}
+	if baseImage == "" {
+		return spec, ownErr
+	}
+	baseClient, err := c.baseClient(ctx, baseImage)
+	if err != nil {
+		log.Printf("Failed to create the artifact client of base image %s of %s, not inheriting requirements: %v", baseImage, c.image, err)
+		return spec, ownErr
+	}
+	baseSpec, err := baseClient.FetchCompatibilitySpec(ctx)
+	if err != nil {
+		if !isNoCompatibilityArtifactError(err) {
+			log.Printf("Failed to fetch the compatibility spec of base image %s of %s, not inheriting requirements: %v", baseImage, c.image, err)
+		}
+		return spec, ownErr
+	}
+	if baseSpec != nil {
+		log.Printf("Image %s inherits %d compatibility sets from base image %s", c.image, len(baseSpec.Compatibilties), baseImage)
+	}
+	return mergeCompatibilitySpecs(spec, baseSpec), nil
+}
+
+// mergeCompatibilitySpecs returns the union of the compatibility sets of the
+// specs, dropping duplicates. The version of the first spec is kept.
+func mergeCompatibilitySpecs(specs ...*compatv1alpha1.Spec) *compatv1alpha1.Spec {
+	var merged *compatv1alpha1.Spec
+	for _, spec := range specs {
+		if spec == nil {
+			continue
+		}
+		if merged == nil {
+			merged = &compatv1alpha1.Spec{Version: spec.Version}
+		}
+		for _, comp := range spec.Compatibilties {
+			duplicate := false
+			for _, existing := range merged.Compatibilties {
+				if reflect.DeepEqual(existing, comp) {
+					duplicate = true
+					break
+				}
+			}
+			if !duplicate {
+				merged.Compatibilties = append(merged.Compatibilties, comp)
+			}
+		}
+	}
+	return merged
+}
+
+// fetchBaseImageName returns the base image named in the manifest
+// annotations of the image, or an empty string.
+func fetchBaseImageName(ctx context.Context, ref registry.Reference, client remote.Client, plainHTTP bool) (string, error) {
+	repo, err := remote.NewRepository(ref.String())
+	if err != nil {
+		return "", err
+	}
+	repo.Client = client
+	repo.PlainHTTP = plainHTTP
+
+	_, content, err := oras.FetchBytes(ctx, repo, ref.Reference, oras.DefaultFetchBytesOptions)
+	if err != nil {
+		return "", err
+	}
+	var manifest struct {
+		Annotations map[string]string `json:"annotations"`
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return "", err
+	}
+	return manifest.Annotations[BaseImageNameAnnotation], nil
+}
@@ -0,0 +1,129 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"oras.land/oras-go/v2/errdef"
+	compatv1alpha1 "sigs.k8s.io/node-feature-discovery/api/image-compatibility/v1alpha1"
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
+	artifactcli "sigs.k8s.io/node-feature-discovery/pkg/client-nfd/compat/artifact-client"
+)
+
+func compatibilitySet(name string) compatv1alpha1.Compatibility {
+	return compatv1alpha1.Compatibility{Rules: []nfdv1alpha1.GroupRule{{Name: name}}}
+}
+
+func TestInheritingArtifactClient(t *testing.T) {
+	shared := compatibilitySet("kernel")
+	base := &compatv1alpha1.Spec{Version: "v1alpha1", Compatibilties: []compatv1alpha1.Compatibility{shared, compatibilitySet("cpu")}}
+	noArtifact := errors.New("compatibility artifact not found")
+
+	tests := map[string]struct {
+		own       *MockArtifactClient
+		baseImage string
+		want      []string
+	}{
+		"merged with base": {
+			own:       &MockArtifactClient{spec: &compatv1alpha1.Spec{Version: "v1alpha1", Compatibilties: []compatv1alpha1.Compatibility{compatibilitySet("gpu"), shared}}},
+			baseImage: "docker.io/library/base:v1",
+			want:      []string{"gpu", "kernel", "cpu"},
+		},
+		"inherited only": {
+			own:       &MockArtifactClient{err: noArtifact},
+			baseImage: "docker.io/library/base:v1",
+			want:      []string{"kernel", "cpu"},
+		},
+		"no base image": {
+			own:  &MockArtifactClient{spec: &compatv1alpha1.Spec{Compatibilties: []compatv1alpha1.Compatibility{compatibilitySet("gpu")}}},
+			want: []string{"gpu"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := &inheritingArtifactClient{
+				image:     "docker.io/library/app:v1",
+				own:       tt.own,
+				baseImage: func(context.Context) (string, error) { return tt.baseImage, nil },
+				baseClient: func(_ context.Context, image string) (artifactcli.ArtifactClient, error) {
+					if image != tt.baseImage {
+						t.Errorf("unexpected base image %s", image)
+					}
+					return &MockArtifactClient{spec: base}, nil
+				},
+			}
+			spec, err := client.FetchCompatibilitySpec(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, comp := range spec.Compatibilties {
+				got = append(got, comp.Rules[0].Name)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected compatibility sets %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("expected compatibility sets %v, got %v", tt.want, got)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestInheritingArtifactClient_NoArtifacts(t *testing.T) {
+	noArtifact := errors.New("compatibility artifact not found")
+	client := &inheritingArtifactClient{
+		image:     "docker.io/library/app:v1",
+		own:       &MockArtifactClient{err: noArtifact},
+		baseImage: func(context.Context) (string, error) { return "docker.io/library/base:v1", nil },
+		baseClient: func(context.Context, string) (artifactcli.ArtifactClient, error) {
+			return &MockArtifactClient{err: noArtifact}, nil
+		},
+	}
+	groups, err := NewFeatureGroupManagement(client).TransferFromArtifact(context.Background())
+	if err != nil || groups != nil {
+		t.Errorf("expected no requirements, got %v, %v", groups, err)
+	}
+}
+
+func TestInheritingArtifactClient_BaseImageErrors(t *testing.T) {
+	own := &compatv1alpha1.Spec{Version: "v1alpha1", Compatibilties: []compatv1alpha1.Compatibility{compatibilitySet("gpu")}}
+	baseNotFound := fmt.Errorf("base manifest: %w", errdef.ErrNotFound)
+
+	tests := map[string]*inheritingArtifactClient{
+		"base image lookup fails": {
+			baseImage: func(context.Context) (string, error) { return "", baseNotFound },
+		},
+		"base client fails": {
+			baseImage: func(context.Context) (string, error) { return "private.example.com/base:v1", nil },
+			baseClient: func(context.Context, string) (artifactcli.ArtifactClient, error) {
+				return nil, errors.New("no credential for registry private.example.com")
+			},
+		},
+		"base artifact fetch fails": {
+			baseImage: func(context.Context) (string, error) { return "docker.io/library/deleted:v1", nil },
+			baseClient: func(context.Context, string) (artifactcli.ArtifactClient, error) {
+				return &MockArtifactClient{err: baseNotFound}, nil
+			},
+		},
+	}
+	for name, client := range tests {
+		t.Run(name, func(t *testing.T) {
+			client.image = "docker.io/library/app:v1"
+			client.own = &MockArtifactClient{spec: own}
+			spec, err := client.FetchCompatibilitySpec(context.Background())
+			if err != nil {
+				t.Fatalf("expected the base image error to be ignored, got %v", err)
+			}
+			if len(spec.Compatibilties) != 1 || spec.Compatibilties[0].Rules[0].Name != "gpu" {
+				t.Errorf("expected the image's own requirements, got %+v", spec)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fwk "k8s.io/kube-scheduler/framework"
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// instanceTypeFeatures caches the parsed instance type feature sets of a
+// ConfigMap revision.
+type instanceTypeFeatures struct {
+	resourceVersion string
+	features        map[string]*nfdv1alpha1.Features
+}
+
+// parseInstanceTypeFeatures parses ConfigMap data mapping instance types to
+// NFD feature sets, in the format of the NodeFeature spec.features field.
+func parseInstanceTypeFeatures(data map[string]string) (map[string]*nfdv1alpha1.Features, error) {
+	features := make(map[string]*nfdv1alpha1.Features, len(data))
+	for instanceType, raw := range data {
+		f := nfdv1alpha1.NewFeatures()
+		if err := sigsyaml.Unmarshal([]byte(raw), f); err != nil {
+			return nil, fmt.Errorf("invalid features of instance type %s: %w", instanceType, err)
+		}
+		features[instanceType] = f
+	}
+	return features, nil
+}
+
+// getInstanceTypeFeatures returns the feature sets of the instance types
+// configured in InstanceTypeFeaturesConfigMap.
+func (f *ImageCompatibilityPlugin) getInstanceTypeFeatures(ctx context.Context) (map[string]*nfdv1alpha1.Features, error) {
+	nn, err := parseNamespacedName(f.args.InstanceTypeFeaturesConfigMap)
+	if err != nil {
+		return nil, err
+	}
+	cm, err := f.handle.ClientSet().CoreV1().ConfigMaps(nn.Namespace).Get(ctx, nn.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance type features ConfigMap %s: %w", nn, err)
+	}
+
+	f.instanceTypeFeaturesMutex.Lock()
+	defer f.instanceTypeFeaturesMutex.Unlock()
+	if f.instanceTypeFeatures.features != nil && f.instanceTypeFeatures.resourceVersion == cm.ResourceVersion {
+		return f.instanceTypeFeatures.features, nil
+	}
+	features, err := parseInstanceTypeFeatures(cm.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse instance type features ConfigMap %s: %w", nn, err)
+	}
+	f.instanceTypeFeatures = instanceTypeFeatures{resourceVersion: cm.ResourceVersion, features: features}
+	return features, nil
+}
+
+// compatibleNodesByInstanceType evaluates the images against the feature
+// sets of the node instance types in-process, without creating NodeFeatureGroups.
+// It returns ok=false when the mode is disabled or any node has an instance
+// type missing from the mapping, in which case NFD has to be used instead.
+func (f *ImageCompatibilityPlugin) compatibleNodesByInstanceType(ctx context.Context, images podImages, runtimeClass string, nodes []fwk.NodeInfo) (byPhase map[string]map[string]struct{}, preferred []preferredSet, ok bool, err error) {
+	if f.args.InstanceTypeFeaturesConfigMap == "" || len(nodes) == 0 {
+		return nil, nil, false, nil
+	}
+
+	instanceTypes, err := f.getInstanceTypeFeatures(ctx)
+	if err != nil {
+		log.Printf("Instance type evaluation unavailable, falling back to NodeFeatureGroups: %v", err)
+		return nil, nil, false, nil
+	}
+
+	featuresByNode := make(map[string]*nfdv1alpha1.Features, len(nodes))
+	for _, nodeInfo := range nodes {
+		node := nodeInfo.Node()
+		if node == nil {
+			continue
+		}
+		instanceType := node.Labels[v1.LabelInstanceTypeStable]
+		features, found := instanceTypes[instanceType]
+		if !found {
+			log.Printf("Instance type %q of node %s is not mapped, falling back to NodeFeatureGroups", instanceType, node.Name)
+			return nil, nil, false, nil
+		}
+		featuresByNode[node.Name] = features
+	}
+
+	byPhase, preferred, err = f.evaluateInProcess(ctx, images, runtimeClass, featuresByNode)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return byPhase, preferred, true, nil
+}
+
+// evaluateInProcess evaluates the compatibility rules of the images of each
+// container phase against the features of each node, returning the nodes
+// satisfying the required compatibility sets of each phase and the nodes
+// satisfying each preferred set. Only the compatibility sets for the runtime
+// class apply.
+func (f *ImageCompatibilityPlugin) evaluateInProcess(ctx context.Context, images podImages, runtimeClass string, featuresByNode map[string]*nfdv1alpha1.Features) (byPhase map[string]map[string]struct{}, preferred []preferredSet, err error) {
+	byPhase = make(map[string]map[string]struct{})
+	for _, phase := range containerPhases {
+		if len(images[phase]) == 0 {
+			continue
+		}
+		var phasePreferred []preferredSet
+		byPhase[phase], phasePreferred, err = f.evaluateImagesInProcess(ctx, images[phase], runtimeClass, featuresByNode)
+		if err != nil {
+			return nil, nil, err
+		}
+		preferred = append(preferred, phasePreferred...)
+	}
+	return byPhase, preferred, nil
+}
+
+// evaluateImagesInProcess evaluates the compatibility rules of the images
+// against the features of each node, returning the nodes satisfying the
+// required compatibility sets and the nodes satisfying each preferred set.
+func (f *ImageCompatibilityPlugin) evaluateImagesInProcess(ctx context.Context, images []string, runtimeClass string, featuresByNode map[string]*nfdv1alpha1.Features) (compatibleNodes map[string]struct{}, preferred []preferredSet, err error) {
+	var required []nfdv1alpha1.NodeFeatureGroup
+	for _, image := range images {
+		groups, err := f.cachedCompatibilityGroups(ctx, image)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		imageRequired, imagePreferred := splitGroupsByWeight(selectRuntimeClassGroups(groups, runtimeClass))
+		required = append(required, imageRequired...)
+		for _, group := range imagePreferred {
+			set := preferredSet{weight: compatibilityWeight(&group), nodes: make(map[string]struct{})}
+			for nodeName, features := range featuresByNode {
+				if matchesAllGroups(nodeName, features, []nfdv1alpha1.NodeFeatureGroup{group}) {
+					set.nodes[nodeName] = struct{}{}
+				}
+			}
+			preferred = append(preferred, set)
+		}
+	}
+
+	decide := f.decision()
+	compatibleNodes = make(map[string]struct{}, len(featuresByNode))
+	for nodeName, features := range featuresByNode {
+		if decide(nodeName, requiredSetStatuses(nodeName, features, required)) {
+			compatibleNodes[nodeName] = struct{}{}
+		}
+	}
+	return compatibleNodes, preferred, nil
+}
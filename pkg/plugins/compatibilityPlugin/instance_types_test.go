@@ -0,0 +1,64 @@
+package compatibilityPlugin
+
+import (
+	"testing"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
+)
+
+func TestParseInstanceTypeFeatures(t *testing.T) {
+	data := map[string]string{
+		"m5.large": `
+attributes:
+  cpu.model:
+    elements:
+      vendor_id: Intel
+flags:
+  cpu.cpuid:
+    elements:
+      AVX512F: {}
+`,
+		"m6a.large": `
+attributes:
+  cpu.model:
+    elements:
+      vendor_id: AMD
+`,
+	}
+
+	features, err := parseInstanceTypeFeatures(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := features["m5.large"].Attributes["cpu.model"].Elements["vendor_id"]; got != "Intel" {
+		t.Errorf("expected m5.large vendor Intel, got %q", got)
+	}
+	if _, ok := features["m5.large"].Flags["cpu.cpuid"].Elements["AVX512F"]; !ok {
+		t.Errorf("expected m5.large to have the AVX512F flag")
+	}
+
+	group := nfdv1alpha1.NodeFeatureGroup{
+		Spec: nfdv1alpha1.NodeFeatureGroupSpec{
+			Rules: []nfdv1alpha1.GroupRule{{
+				Name: "avx512",
+				MatchFeatures: nfdv1alpha1.FeatureMatcher{{
+					Feature: "cpu.cpuid",
+					MatchExpressions: &nfdv1alpha1.MatchExpressionSet{
+						"AVX512F": {Op: nfdv1alpha1.MatchExists},
+					},
+				}},
+			}},
+		},
+	}
+	groups := []nfdv1alpha1.NodeFeatureGroup{group}
+	if !matchesAllGroups("m5", features["m5.large"], groups) {
+		t.Errorf("expected m5.large to be compatible")
+	}
+	if matchesAllGroups("m6a", features["m6a.large"], groups) {
+		t.Errorf("expected m6a.large to be incompatible")
+	}
+
+	if _, err := parseInstanceTypeFeatures(map[string]string{"bad": "attributes: ["}); err == nil {
+		t.Errorf("expected error for invalid features")
+	}
+}
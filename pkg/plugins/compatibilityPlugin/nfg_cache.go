@@ -0,0 +1,89 @@
+package compatibilityPlugin
+
+import "sync"
+
+// nfgCacheShards is the number of independently locked shards of the cache.
+const nfgCacheShards = 32
+
+// nfgCache is the image to NFG cache, sharded by image so that concurrent
+// scheduling cycles for different images do not serialize on a single lock.
+type nfgCache struct {
+	shards [nfgCacheShards]nfgCacheShard
+}
+
+type nfgCacheShard struct {
+	mu      sync.RWMutex
+	entries map[string]nfgCacheEntry
+}
+
+func newNFGCache() *nfgCache {
+	c := &nfgCache{}
+	for i := range c.shards {
+		c.shards[i].entries = make(map[string]nfgCacheEntry)
+	}
+	return c
+}
+
+func (c *nfgCache) shard(image string) *nfgCacheShard {
+	// Inline FNV-1a, hash/fnv would allocate on every lookup
+	h := uint32(2166136261)
+	for i := 0; i < len(image); i++ {
+		h ^= uint32(image[i])
+		h *= 16777619
+	}
+	return &c.shards[h%nfgCacheShards]
+}
+
+// get returns the cache entry of image.
+func (c *nfgCache) get(image string) (nfgCacheEntry, bool) {
+	s := c.shard(image)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entry, ok := s.entries[image]
+	return entry, ok
+}
+
+// set stores the cache entry of image.
+func (c *nfgCache) set(image string, entry nfgCacheEntry) {
+	s := c.shard(image)
+	s.mu.Lock()
+	s.entries[image] = entry
+	s.mu.Unlock()
+}
+
+// delete removes the cache entry of image.
+func (c *nfgCache) delete(image string) {
+	s := c.shard(image)
+	s.mu.Lock()
+	delete(s.entries, image)
+	s.mu.Unlock()
+}
+
+// snapshot returns a copy of all cache entries.
+func (c *nfgCache) snapshot() map[string]nfgCacheEntry {
+	entries := make(map[string]nfgCacheEntry)
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.RLock()
+		for image, entry := range s.entries {
+			entries[image] = entry
+		}
+		s.mu.RUnlock()
+	}
+	return entries
+}
+
+// update calls fn for every entry, one shard at a time, and stores the
+// entries for which fn returns true.
+func (c *nfgCache) update(fn func(image string, entry nfgCacheEntry) (nfgCacheEntry, bool)) {
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.Lock()
+		for image, entry := range s.entries {
+			if updated, ok := fn(image, entry); ok {
+				s.entries[image] = updated
+			}
+		}
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,93 @@
+package compatibilityPlugin
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestNFGCache(t *testing.T) {
+	c := newNFGCache()
+	for i := range 100 {
+		c.set(fmt.Sprintf("image-%d", i), nfgCacheEntry{nfgNames: []string{"nfg-a", fmt.Sprintf("nfg-%d", i)}})
+	}
+
+	if entry, ok := c.get("image-42"); !ok || !reflect.DeepEqual(entry.nfgNames, []string{"nfg-a", "nfg-42"}) {
+		t.Errorf("unexpected entry for image-42: %+v, %v", entry, ok)
+	}
+	c.delete("image-42")
+	if _, ok := c.get("image-42"); ok {
+		t.Errorf("expected image-42 to be deleted")
+	}
+
+	c.update(func(image string, entry nfgCacheEntry) (nfgCacheEntry, bool) {
+		entry.nfgNames = entry.nfgNames[1:]
+		return entry, true
+	})
+	entries := c.snapshot()
+	if len(entries) != 99 {
+		t.Fatalf("expected 99 entries, got %d", len(entries))
+	}
+	if got := entries["image-7"].nfgNames; !reflect.DeepEqual(got, []string{"nfg-7"}) {
+		t.Errorf("expected updated NFGs [nfg-7], got %v", got)
+	}
+}
+
+// singleLockNFGCache is the previous mutex-guarded map, kept as the
+// benchmark baseline.
+type singleLockNFGCache struct {
+	mu      sync.RWMutex
+	entries map[string]nfgCacheEntry
+}
+
+func (c *singleLockNFGCache) get(image string) (nfgCacheEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.entries[image]
+	return entry, ok
+}
+
+func (c *singleLockNFGCache) set(image string, entry nfgCacheEntry) {
+	c.mu.Lock()
+	c.entries[image] = entry
+	c.mu.Unlock()
+}
+
+// benchmarkCache runs a read-mostly workload, one write per ten operations,
+// from many goroutines over 1000 images.
+func benchmarkCache(b *testing.B, get func(string) (nfgCacheEntry, bool), set func(string, nfgCacheEntry)) {
+	images := make([]string, 1000)
+	for i := range images {
+		images[i] = fmt.Sprintf("registry.example.com/app-%d:v1", i)
+		set(images[i], nfgCacheEntry{nfgNames: []string{"nfg"}})
+	}
+
+	var seed atomic.Uint32
+	b.SetParallelism(64)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := int(seed.Add(7919))
+		for pb.Next() {
+			image := images[i%len(images)]
+			if i%10 == 0 {
+				set(image, nfgCacheEntry{nfgNames: []string{"nfg"}})
+			} else {
+				get(image)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkNFGCache(b *testing.B) {
+	b.Run("single-lock", func(b *testing.B) {
+		c := &singleLockNFGCache{entries: make(map[string]nfgCacheEntry)}
+		benchmarkCache(b, c.get, c.set)
+	})
+	b.Run("sharded", func(b *testing.B) {
+		c := newNFGCache()
+		benchmarkCache(b, c.get, c.set)
+	})
+}
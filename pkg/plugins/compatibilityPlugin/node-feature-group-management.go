@@ -2,20 +2,56 @@ package compatibilityPlugin
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	k8sclient "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"oras.land/oras-go/v2/registry/remote/errcode"
 	nfdclientset "sigs.k8s.io/node-feature-discovery/api/generated/clientset/versioned"
+	compatv1alpha1 "sigs.k8s.io/node-feature-discovery/api/image-compatibility/v1alpha1"
 	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
 	artifactcli "sigs.k8s.io/node-feature-discovery/pkg/client-nfd/compat/artifact-client"
 )
 
+// ErrArtifactFetchTimeout is returned when fetching the compatibility artifact
+// does not complete within the configured timeout.
+var ErrArtifactFetchTimeout = errors.New("compatibility artifact fetch timed out")
+
+// FetchOptions bound fetching the compatibility artifact from the registry.
+type FetchOptions struct {
+	// Timeout bounds each fetch attempt. Zero means no timeout.
+	Timeout time.Duration
+	// Attempts is the maximum number of fetch attempts on transient registry
+	// errors. Values below 1 mean a single attempt.
+	Attempts int
+	// Backoff is the delay before the first retry, doubled for every further retry.
+	Backoff time.Duration
+}
+
 type FeatureGroupManagement struct {
 	artifactClient artifactcli.ArtifactClient
+	fetchOptions   FetchOptions
 	k8sClient      k8sclient.Interface
 	namespace      string
+
+	// createConcurrency bounds the concurrent NodeFeatureGroup creations
+	createConcurrency int
+	// reusable are existing groups keyed by compatibility set hash, reused
+	// instead of creating groups for unchanged compatibility sets
+	reusable map[string]nfdv1alpha1.NodeFeatureGroup
 }
 
 // NewFeatureGroupManagement creates a new FeatureGroupManagement instance
@@ -25,21 +61,42 @@ func NewFeatureGroupManagement(artifactClient artifactcli.ArtifactClient) *Featu
 	}
 }
 
+// WithFetchOptions sets the timeout and retry behaviour of artifact fetches.
+func (fgm *FeatureGroupManagement) WithFetchOptions(opts FetchOptions) *FeatureGroupManagement {
+	fgm.fetchOptions = opts
+	return fgm
+}
+
+// WithCreateConcurrency sets how many NodeFeatureGroups of an artifact are
+// created concurrently. Values below 1 mean one at a time.
+func (fgm *FeatureGroupManagement) WithCreateConcurrency(n int) *FeatureGroupManagement {
+	fgm.createConcurrency = n
+	return fgm
+}
+
+// WithReusableGroups sets existing groups, keyed by the hash of their
+// compatibility set, that are returned instead of creating new groups for
+// the same compatibility sets.
+func (fgm *FeatureGroupManagement) WithReusableGroups(reusable map[string]nfdv1alpha1.NodeFeatureGroup) *FeatureGroupManagement {
+	fgm.reusable = reusable
+	return fgm
+}
+
 // CreateNodeFeatureGroupsFromArtifact creates temporary NodeFeatureGroup CRs based on
 // compatibility spec in artifact. These CRs are owned by the Pod and will be automatically
 // deleted when the Pod is deleted via Kubernetes garbage collection.
 func (fgm *FeatureGroupManagement) CreateNodeFeatureGroupsFromArtifact(ctx context.Context, cli nfdclientset.Interface, pod *v1.Pod, namespace string) ([]nfdv1alpha1.NodeFeatureGroup, error) {
 	nodeFeatureGroups, err := fgm.TransferFromArtifact(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to transfer from artifact: %v", err)
+		return nil, fmt.Errorf("failed to transfer from artifact: %w", err)
 	}
 
 	// Note: Cross-namespace OwnerReference may cause garbage collection issues
 	// We use labels to associate with Pod instead of cross-namespace OwnerReference
 	// This avoids Kubernetes garbage collector problems
 
-	nfgs := make([]nfdv1alpha1.NodeFeatureGroup, 0)
-	for _, nodeFeatureGroup := range nodeFeatureGroups {
+	for i := range nodeFeatureGroups {
+		nodeFeatureGroup := &nodeFeatureGroups[i]
 		// Set metadata and labels for lifecycle management
 		if nodeFeatureGroup.ObjectMeta.Annotations == nil {
 			nodeFeatureGroup.ObjectMeta.Annotations = make(map[string]string)
@@ -52,39 +109,240 @@ func (fgm *FeatureGroupManagement) CreateNodeFeatureGroupsFromArtifact(ctx conte
 		nodeFeatureGroup.ObjectMeta.Labels["managed-by"] = PluginName
 		nodeFeatureGroup.ObjectMeta.Labels["temporary"] = "true"
 		// Use labels to associate with Pod
-		nodeFeatureGroup.ObjectMeta.Labels["pod-name"] = pod.Name
-		nodeFeatureGroup.ObjectMeta.Labels["pod-namespace"] = pod.Namespace
-		nodeFeatureGroup.ObjectMeta.Labels["pod-uid"] = string(pod.UID)
+		maps.Copy(nodeFeatureGroup.ObjectMeta.Labels, podLabels(pod))
+		hash, err := compatibilitySetHash(nodeFeatureGroup)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash compatibility set %d: %w", i, err)
+		}
+		nodeFeatureGroup.ObjectMeta.Annotations[NFGSpecHashAnnotation] = hash
 
 		// Do not set cross-namespace OwnerReferences
 		// nodeFeatureGroup.ObjectMeta.OwnerReferences = []metav1.OwnerReference{ownerRef}
+	}
+
+	// Create NodeFeatureGroup CRs in nfd-master namespace, keeping the order
+	// of the compatibility sets
+	created := make([]*nfdv1alpha1.NodeFeatureGroup, len(nodeFeatureGroups))
+	reused := make([]*nfdv1alpha1.NodeFeatureGroup, len(nodeFeatureGroups))
+	errs := make([]error, len(nodeFeatureGroups))
+	sem := make(chan struct{}, max(fgm.createConcurrency, 1))
+	var wg sync.WaitGroup
+	for i := range nodeFeatureGroups {
+		reusable, found := fgm.reusable[nodeFeatureGroups[i].Annotations[NFGSpecHashAnnotation]]
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if found {
+				// The group now belongs to this pod, so that the orphan
+				// cleanup keeps it as long as the pod lives
+				nfg, err := relabelNodeFeatureGroup(ctx, cli, namespace, reusable.Name, pod)
+				if err == nil {
+					log.Printf("Compatibility set %d%s is unchanged, reusing NodeFeatureGroup %s", i, compatibilitySetTag(&nodeFeatureGroups[i]), nfg.Name)
+					reused[i] = nfg
+					return
+				}
+				log.Printf("Cannot reuse NodeFeatureGroup %s for compatibility set %d%s, creating a new one: %v", reusable.Name, i, compatibilitySetTag(&nodeFeatureGroups[i]), err)
+			}
+			klog.FromContext(ctx).V(5).Info("creating NodeFeatureGroup", "name", nodeFeatureGroups[i].Name,
+				"generateName", nodeFeatureGroups[i].GenerateName, "namespace", namespace)
+			nfg, err := cli.NfdV1alpha1().NodeFeatureGroups(namespace).Create(ctx, &nodeFeatureGroups[i], metav1.CreateOptions{})
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			created[i] = nfg
+		}()
+	}
+	wg.Wait()
+
+	var failed []error
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, fmt.Errorf("compatibility set %d%s: %w", i, compatibilitySetTag(&nodeFeatureGroups[i]), err))
+		}
+	}
+	if len(failed) > 0 {
+		deleteCreatedNodeFeatureGroups(ctx, cli, namespace, created)
+		return nil, fmt.Errorf("failed to create NodeFeatureGroups for %d of %d compatibility sets: %w",
+			len(failed), len(nodeFeatureGroups), errors.Join(failed...))
+	}
 
-		fmt.Printf("Processing NodeFeatureGroup : Name=%q, GenerateName=%q, Namespace=%q\n",
-			nodeFeatureGroup.ObjectMeta.Name, nodeFeatureGroup.ObjectMeta.GenerateName, nodeFeatureGroup.ObjectMeta.Namespace)
-		// Create NodeFeatureGroup CRs in nfd-master namespace
-		if nfg, err := cli.NfdV1alpha1().NodeFeatureGroups(namespace).Create(ctx, &nodeFeatureGroup, metav1.CreateOptions{}); err != nil {
-			return nil, fmt.Errorf("failed to create NodeFeatureGroup: %v", err)
-		} else {
-			nfgs = append(nfgs, *nfg)
+	nfgs := make([]nfdv1alpha1.NodeFeatureGroup, 0, len(created))
+	for i, nfg := range created {
+		if nfg == nil {
+			nfg = reused[i]
 		}
+		nfgs = append(nfgs, *nfg)
 	}
 	return nfgs, nil
 }
 
+// podLabels returns the labels associating a NodeFeatureGroup with the pod it
+// is used for, checked by the orphan cleanup.
+func podLabels(pod *v1.Pod) map[string]string {
+	return map[string]string{
+		"pod-name":      pod.Name,
+		"pod-namespace": pod.Namespace,
+		"pod-uid":       string(pod.UID),
+	}
+}
+
+// relabelNodeFeatureGroup associates an existing NodeFeatureGroup with the pod.
+func relabelNodeFeatureGroup(ctx context.Context, cli nfdclientset.Interface, namespace, name string, pod *v1.Pod) (*nfdv1alpha1.NodeFeatureGroup, error) {
+	patch, err := json.Marshal(map[string]any{"metadata": map[string]any{"labels": podLabels(pod)}})
+	if err != nil {
+		return nil, err
+	}
+	return cli.NfdV1alpha1().NodeFeatureGroups(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+}
+
+// compatibilitySetTag describes the tag of the compatibility set of the group
+// for error messages, if it has one.
+func compatibilitySetTag(nfg *nfdv1alpha1.NodeFeatureGroup) string {
+	if tag := nfg.Annotations[NFGTagAnnotation]; tag != "" {
+		return fmt.Sprintf(" (tag %q)", tag)
+	}
+	return ""
+}
+
+// deleteCreatedNodeFeatureGroups rolls back the groups created for an artifact
+// whose other groups failed, so that no orphans are left behind. Deletion
+// failures are logged, the groups are then removed by the periodic cleanup.
+func deleteCreatedNodeFeatureGroups(ctx context.Context, cli nfdclientset.Interface, namespace string, created []*nfdv1alpha1.NodeFeatureGroup) {
+	// Roll back even if the creation was interrupted
+	ctx = context.WithoutCancel(ctx)
+	for _, nfg := range created {
+		if nfg == nil {
+			continue
+		}
+		if err := cli.NfdV1alpha1().NodeFeatureGroups(namespace).Delete(ctx, nfg.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			log.Printf("Failed to roll back NodeFeatureGroup %s/%s: %v", namespace, nfg.Name, err)
+			continue
+		}
+		log.Printf("Rolled back NodeFeatureGroup %s/%s", namespace, nfg.Name)
+	}
+}
+
 // Transfer the compatibility artifact to node-feature-group
 func (fgm *FeatureGroupManagement) TransferFromArtifact(ctx context.Context) ([]nfdv1alpha1.NodeFeatureGroup, error) {
 	var nodeFeatureGroups []nfdv1alpha1.NodeFeatureGroup
-	spec, err := fgm.artifactClient.FetchCompatibilitySpec(ctx)
+	spec, err := fgm.fetchCompatibilitySpec(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch compatibility spec: %v", err)
+		if isNoCompatibilityArtifactError(err) {
+			log.Printf("Image has no compatibility artifact, no requirements")
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to fetch compatibility spec: %w", err)
+	}
+	if spec == nil {
+		// The artifact client returns no spec when the registry does not
+		// support listing referrers, the image has no known requirements
+		log.Printf("No compatibility spec found, no requirements")
+		return nil, nil
 	}
 	for _, comp := range spec.Compatibilties {
+		// Deep copy the rules so that match expressions, including their
+		// operators and value types, are carried over as-is without aliasing
+		// the fetched spec
+		rules := make([]nfdv1alpha1.GroupRule, 0, len(comp.Rules))
+		for _, rule := range comp.Rules {
+			rules = append(rules, *rule.DeepCopy())
+		}
 		nodeFeatureGroup := nfdv1alpha1.NodeFeatureGroup{
 			Spec: nfdv1alpha1.NodeFeatureGroupSpec{
-				Rules: comp.Rules,
+				Rules: rules,
 			},
 		}
+		// Keep the weight and tag of the compatibility set for scoring
+		if comp.Weight != 0 || comp.Tag != "" {
+			nodeFeatureGroup.ObjectMeta.Annotations = map[string]string{}
+			if comp.Weight != 0 {
+				nodeFeatureGroup.ObjectMeta.Annotations[NFGWeightAnnotation] = strconv.Itoa(comp.Weight)
+			}
+			if comp.Tag != "" {
+				nodeFeatureGroup.ObjectMeta.Annotations[NFGTagAnnotation] = comp.Tag
+			}
+		}
 		nodeFeatureGroups = append(nodeFeatureGroups, nodeFeatureGroup)
 	}
 	return nodeFeatureGroups, nil
 }
+
+// fetchCompatibilitySpec fetches the compatibility spec, retrying transient
+// registry errors with exponential backoff. Timeouts are not retried so that
+// a hung registry does not block the caller for longer than the timeout.
+func (fgm *FeatureGroupManagement) fetchCompatibilitySpec(ctx context.Context) (*compatv1alpha1.Spec, error) {
+	attempts := max(fgm.fetchOptions.Attempts, 1)
+	backoff := fgm.fetchOptions.Backoff
+
+	for attempt := 1; ; attempt++ {
+		spec, err := fgm.fetchCompatibilitySpecOnce(ctx)
+		if err == nil || attempt >= attempts || !isRetryableFetchError(err) {
+			return spec, err
+		}
+		log.Printf("Fetching compatibility artifact failed (attempt %d/%d), retrying in %v: %v", attempt, attempts, backoff, err)
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// fetchCompatibilitySpecOnce performs a single fetch bounded by the configured
+// timeout. The fetch runs in its own goroutine so that the timeout holds even if
+// the artifact client does not honour context cancellation.
+func (fgm *FeatureGroupManagement) fetchCompatibilitySpecOnce(ctx context.Context) (*compatv1alpha1.Spec, error) {
+	timeout := fgm.fetchOptions.Timeout
+	if timeout <= 0 {
+		return fgm.artifactClient.FetchCompatibilitySpec(ctx)
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		spec *compatv1alpha1.Spec
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		spec, err := fgm.artifactClient.FetchCompatibilitySpec(fetchCtx)
+		done <- result{spec: spec, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil && ctx.Err() == nil && errors.Is(fetchCtx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w after %v: %w", ErrArtifactFetchTimeout, timeout, r.err)
+		}
+		return r.spec, r.err
+	case <-fetchCtx.Done():
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w after %v: %w", ErrArtifactFetchTimeout, timeout, fetchCtx.Err())
+	}
+}
+
+// isNoCompatibilityArtifactError reports whether the image exists but has no
+// compatibility artifact attached.
+func isNoCompatibilityArtifactError(err error) bool {
+	return strings.Contains(err.Error(), "compatibility artifact not found")
+}
+
+// isRetryableFetchError reports whether an artifact fetch failed for a reason
+// that may go away on retry, such as network errors or registry 5xx responses.
+func isRetryableFetchError(err error) bool {
+	if errors.Is(err, ErrArtifactFetchTimeout) || isImageNotFoundError(err) {
+		return false
+	}
+	var errResp *errcode.ErrorResponse
+	if errors.As(err, &errResp) {
+		return errResp.StatusCode >= http.StatusInternalServerError || errResp.StatusCode == http.StatusTooManyRequests
+	}
+	return isTransientError(err)
+}
@@ -2,23 +2,45 @@ package compatibilityPlugin
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"net/http"
 	"os"
 	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"gopkg.in/yaml.v3"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+	"oras.land/oras-go/v2/registry/remote/errcode"
+	nfdfake "sigs.k8s.io/node-feature-discovery/api/generated/clientset/versioned/fake"
 	compatv1alpha1 "sigs.k8s.io/node-feature-discovery/api/image-compatibility/v1alpha1"
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
+	sigsyaml "sigs.k8s.io/yaml"
 )
 
 // MockArtifactClient mocks artifactcli.ArtifactClient
 type MockArtifactClient struct {
 	spec *compatv1alpha1.Spec
 	err  error
+	// delay blocks each fetch, ignoring context cancellation like a hung registry
+	delay time.Duration
+	// failures is the number of leading fetches that fail with err
+	failures int
+	calls    atomic.Int32
 }
 
 func (m *MockArtifactClient) FetchCompatibilitySpec(ctx context.Context) (*compatv1alpha1.Spec, error) {
-	if m.err != nil {
+	calls := int(m.calls.Add(1))
+	time.Sleep(m.delay)
+	if m.err != nil && (m.failures == 0 || calls <= m.failures) {
 		return nil, m.err
 	}
 	return m.spec, nil
@@ -85,3 +107,188 @@ func TestTransferFromArtifact_FetchError(t *testing.T) {
 		t.Errorf("expected nil nodeFeatureGroups, got %v", nodeFeatureGroups)
 	}
 }
+
+func TestTransferFromArtifact_NoRequirements(t *testing.T) {
+	for name, client := range map[string]*MockArtifactClient{
+		"no compatibility artifact": {err: errors.New("compatibility artifact not found")},
+		"no spec":                   {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			nodeFeatureGroups, err := NewFeatureGroupManagement(client).TransferFromArtifact(context.Background())
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if len(nodeFeatureGroups) != 0 {
+				t.Errorf("expected no NodeFeatureGroups, got %v", nodeFeatureGroups)
+			}
+		})
+	}
+}
+
+func TestTransferFromArtifact_FetchTimeout(t *testing.T) {
+	client := &MockArtifactClient{spec: &compatv1alpha1.Spec{}, delay: time.Second}
+	fgm := NewFeatureGroupManagement(client).WithFetchOptions(FetchOptions{
+		Timeout:  50 * time.Millisecond,
+		Attempts: 3,
+	})
+
+	start := time.Now()
+	_, err := fgm.TransferFromArtifact(context.Background())
+	if !errors.Is(err, ErrArtifactFetchTimeout) {
+		t.Fatalf("expected ErrArtifactFetchTimeout, got %v", err)
+	}
+	if !isTransientError(err) {
+		t.Errorf("expected timeout to be a transient error")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("fetch was not bounded by the timeout, took %v", elapsed)
+	}
+	if client.calls.Load() != 1 {
+		t.Errorf("expected timeouts not to be retried, got %d calls", client.calls.Load())
+	}
+}
+
+func TestTransferFromArtifact_RetriesTransientErrors(t *testing.T) {
+	client := &MockArtifactClient{
+		spec:     &compatv1alpha1.Spec{},
+		err:      &errcode.ErrorResponse{StatusCode: http.StatusServiceUnavailable},
+		failures: 2,
+	}
+	fgm := NewFeatureGroupManagement(client).WithFetchOptions(FetchOptions{
+		Timeout:  time.Second,
+		Attempts: 3,
+		Backoff:  time.Millisecond,
+	})
+
+	if _, err := fgm.TransferFromArtifact(context.Background()); err != nil {
+		t.Fatalf("expected success after retries, got %v", err)
+	}
+	if client.calls.Load() != 3 {
+		t.Errorf("expected 3 calls, got %d", client.calls.Load())
+	}
+
+	// Not found is permanent and must not be retried
+	client = &MockArtifactClient{err: &errcode.ErrorResponse{StatusCode: http.StatusNotFound}}
+	fgm = NewFeatureGroupManagement(client).WithFetchOptions(FetchOptions{Attempts: 3, Backoff: time.Millisecond})
+	if _, err := fgm.TransferFromArtifact(context.Background()); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if client.calls.Load() != 1 {
+		t.Errorf("expected no retries for not found, got %d calls", client.calls.Load())
+	}
+}
+
+func TestTransferFromArtifact_PreservesComparisonOperators(t *testing.T) {
+	input, err := os.ReadFile("../../../scripts/compatibility-artifact-driver-version.yaml")
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+
+	// Decode the same way the artifact client does
+	var mockSpec compatv1alpha1.Spec
+	if err := sigsyaml.Unmarshal(input, &mockSpec); err != nil {
+		t.Fatalf("failed to unmarshal mock spec: %v", err)
+	}
+	if len(mockSpec.Compatibilties) != 1 {
+		t.Fatalf("expected 1 compatibility set, got %d", len(mockSpec.Compatibilties))
+	}
+	var wantRules []nfdv1alpha1.GroupRule
+	for _, rule := range mockSpec.Compatibilties[0].Rules {
+		wantRules = append(wantRules, *rule.DeepCopy())
+	}
+
+	fgm := &FeatureGroupManagement{
+		artifactClient: &MockArtifactClient{spec: &mockSpec},
+	}
+	nodeFeatureGroups, err := fgm.TransferFromArtifact(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(nodeFeatureGroups) != 1 {
+		t.Fatalf("expected 1 NodeFeatureGroup, got %d", len(nodeFeatureGroups))
+	}
+
+	// Round-trip through JSON as the API server would on create
+	raw, err := json.Marshal(nodeFeatureGroups[0])
+	if err != nil {
+		t.Fatalf("failed to marshal NodeFeatureGroup: %v", err)
+	}
+	var created nfdv1alpha1.NodeFeatureGroup
+	if err := json.Unmarshal(raw, &created); err != nil {
+		t.Fatalf("failed to unmarshal NodeFeatureGroup: %v", err)
+	}
+
+	if !reflect.DeepEqual(created.Spec.Rules, wantRules) {
+		t.Fatalf("rules changed during transfer:\ngot:  %+v\nwant: %+v", created.Spec.Rules, wantRules)
+	}
+
+	rule := created.Spec.Rules[0]
+	full := (*rule.MatchFeatures[0].MatchExpressions)["full"]
+	if full.Op != nfdv1alpha1.MatchGeLe || full.Type != nfdv1alpha1.TypeVersion || len(full.Value) != 2 {
+		t.Errorf("unexpected kernel version expression: %+v", full)
+	}
+	driver := (*rule.MatchFeatures[1].MatchExpressions)["nvidia.driver.version"]
+	if driver.Op != nfdv1alpha1.MatchGe || driver.Type != nfdv1alpha1.TypeVersion {
+		t.Errorf("unexpected driver version expression: %+v", driver)
+	}
+	if op := (*rule.MatchAny[0].MatchFeatures[0].MatchExpressions)["family"].Op; op != nfdv1alpha1.MatchGtLt {
+		t.Errorf("expected GtLt operator in matchAny, got %q", op)
+	}
+	if op := (*rule.MatchAny[1].MatchFeatures[0].MatchExpressions)["node_count"].Op; op != nfdv1alpha1.MatchLt {
+		t.Errorf("expected Lt operator in matchAny, got %q", op)
+	}
+
+	// The transferred rules must not alias the fetched spec
+	(*nodeFeatureGroups[0].Spec.Rules[0].MatchFeatures[0].MatchExpressions)["major"].Value[0] = "99"
+	if got := (*mockSpec.Compatibilties[0].Rules[0].MatchFeatures[0].MatchExpressions)["major"].Value[0]; got != "4" {
+		t.Errorf("fetched spec was modified through the NodeFeatureGroup, got %q", got)
+	}
+}
+
+func TestCreateNodeFeatureGroupsRollsBackOnFailure(t *testing.T) {
+	spec := &compatv1alpha1.Spec{Version: "v1alpha1"}
+	for _, tag := range []string{"a", "b", "c", "d", "e"} {
+		spec.Compatibilties = append(spec.Compatibilties, compatv1alpha1.Compatibility{
+			Tag:   tag,
+			Rules: []nfdv1alpha1.GroupRule{{Name: tag}},
+		})
+	}
+
+	// The fake clientset ignores GenerateName, name the groups in the reactor
+	// and fail the 3rd create
+	nfdCli := nfdfake.NewSimpleClientset()
+	var creates atomic.Int32
+	var createdNames []string
+	var mu sync.Mutex
+	nfdCli.PrependReactor("create", "nodefeaturegroups", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		n := creates.Add(1)
+		if n == 3 {
+			return true, nil, errors.New("injected create failure")
+		}
+		nfg := action.(k8stesting.CreateAction).GetObject().(*nfdv1alpha1.NodeFeatureGroup)
+		nfg.Name = nfg.GenerateName + string(rune('a'+n))
+		mu.Lock()
+		createdNames = append(createdNames, nfg.Name)
+		mu.Unlock()
+		return false, nil, nil
+	})
+
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+	fgm := NewFeatureGroupManagement(&MockArtifactClient{spec: spec}).WithCreateConcurrency(2)
+	_, err := fgm.CreateNodeFeatureGroupsFromArtifact(context.Background(), nfdCli, pod, "nfd")
+	if err == nil {
+		t.Fatal("expected the 3rd create to fail")
+	}
+	if !strings.Contains(err.Error(), "1 of 5 compatibility sets") || !strings.Contains(err.Error(), "injected create failure") {
+		t.Errorf("expected an aggregated error naming the failed set, got %v", err)
+	}
+
+	if len(createdNames) != 4 {
+		t.Fatalf("expected the other 4 groups to be created, got %v", createdNames)
+	}
+	for _, name := range createdNames {
+		if _, err := nfdCli.NfdV1alpha1().NodeFeatureGroups("nfd").Get(context.Background(), name, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+			t.Errorf("expected NodeFeatureGroup %s to be rolled back, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+var (
+	// nodeRevalidations counts the verdicts re-evaluated after a node
+	// revalidation annotation change, by result.
+	nodeRevalidations = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      "image_compatibility",
+			Name:           "node_revalidations_total",
+			Help:           "Number of image verdicts re-evaluated after a change of the node revalidation annotation, by result.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"result"},
+	)
+	registerMetricsOnce sync.Once
+)
+
+// registerMetrics registers the plugin metrics with the scheduler registry.
+func registerMetrics() {
+	registerMetricsOnce.Do(func() {
+		legacyregistry.MustRegister(nodeRevalidations)
+	})
+}
+
+// watchNodeRevalidation re-evaluates the verdict labels of a node whenever
+// its RevalidationAnnotation changes, e.g. after a driver upgrade.
+func (f *ImageCompatibilityPlugin) watchNodeRevalidation(ctx context.Context) error {
+	registerMetrics()
+	informer := f.handle.SharedInformerFactory().Core().V1().Nodes().Informer()
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldNode, ok := oldObj.(*v1.Node)
+			if !ok {
+				return
+			}
+			newNode, ok := newObj.(*v1.Node)
+			if !ok {
+				return
+			}
+			annotation := f.args.RevalidationAnnotation
+			if oldNode.Annotations[annotation] == newNode.Annotations[annotation] {
+				return
+			}
+			log.Printf("Annotation %s of node %s changed, revalidating its image verdicts", annotation, newNode.Name)
+			go f.revalidateNode(ctx, newNode)
+		},
+	})
+	return err
+}
+
+// revalidateNode re-evaluates the verdict of every image labeled on the node
+// whose NodeFeatureGroups are still cached.
+func (f *ImageCompatibilityPlugin) revalidateNode(ctx context.Context, node *v1.Node) {
+	namespace, err := f.getNfdMasterNamespace(ctx)
+	if err != nil {
+		log.Printf("Cannot revalidate node %s: failed to get nfd-master namespace: %v", node.Name, err)
+		return
+	}
+
+	imageNFGs, skipped := f.cachedVerdictNFGs(node)
+	nodeRevalidations.WithLabelValues("revalidated").Add(float64(len(imageNFGs)))
+	nodeRevalidations.WithLabelValues("skipped").Add(float64(skipped))
+	log.Printf("Revalidating %d image verdicts of node %s, %d images are no longer cached", len(imageNFGs), node.Name, skipped)
+	f.recordNodeVerdicts(ctx, namespace, imageNFGs, []string{node.Name})
+}
+
+// cachedVerdictNFGs returns the cached NodeFeatureGroups of the images with
+// a verdict label on the node, and the number of images without any.
+func (f *ImageCompatibilityPlugin) cachedVerdictNFGs(node *v1.Node) (map[string][]string, int) {
+	var images []nodeVerdictImage
+	if raw, ok := node.Annotations[NodeVerdictImagesAnnotation]; ok {
+		if err := json.Unmarshal([]byte(raw), &images); err != nil {
+			log.Printf("Ignoring invalid %s annotation on node %s: %v", NodeVerdictImagesAnnotation, node.Name, err)
+			return nil, 0
+		}
+	}
+
+	imageNFGs := make(map[string][]string, len(images))
+	skipped := 0
+	for _, img := range images {
+		key := img.Image
+		if digestRef, found := f.tagDigests.get(img.Image); found {
+			key = digestRef
+		}
+		entry, found := f.imageToNFGCache.get(key)
+		if !found || entry.noRequirements {
+			skipped++
+			continue
+		}
+		imageNFGs[img.Image] = entry.nfgNames
+	}
+	return imageNFGs, skipped
+}
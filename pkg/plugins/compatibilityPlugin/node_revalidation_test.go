@@ -0,0 +1,29 @@
+package compatibilityPlugin
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCachedVerdictNFGs(t *testing.T) {
+	plugin := &ImageCompatibilityPlugin{imageToNFGCache: newNFGCache(), tagDigests: newDigestIndex()}
+	plugin.updateCacheForImage("docker.io/library/gpu:v1", []string{"image-compat-gpu-x"})
+	plugin.updateCacheForImage("docker.io/library/plain:v1", nil)
+
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name: "n1",
+		Annotations: map[string]string{NodeVerdictImagesAnnotation: `[` +
+			`{"hash":"a","image":"docker.io/library/gpu:v1"},` +
+			`{"hash":"b","image":"docker.io/library/plain:v1"},` +
+			`{"hash":"c","image":"docker.io/library/evicted:v1"}]`},
+	}}
+
+	imageNFGs, skipped := plugin.cachedVerdictNFGs(node)
+	want := map[string][]string{"docker.io/library/gpu:v1": {"image-compat-gpu-x"}}
+	if !reflect.DeepEqual(imageNFGs, want) || skipped != 2 {
+		t.Errorf("cachedVerdictNFGs() = %v, %d, want %v, 2", imageNFGs, skipped, want)
+	}
+}
@@ -0,0 +1,211 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// NodeVerdictLabelPrefix prefixes the node labels holding the last
+	// compatibility verdict of an image, keyed by a hash of the image.
+	NodeVerdictLabelPrefix = "image-compat.scheduler/"
+	// NodeVerdictImagesAnnotation lists the images that have a verdict label
+	// on the node, oldest first.
+	NodeVerdictImagesAnnotation = "image-compat.scheduler/images"
+	// NodeVerdictCompatible and NodeVerdictIncompatible are the verdict label values.
+	NodeVerdictCompatible   = "compatible"
+	NodeVerdictIncompatible = "incompatible"
+	// DefaultMaxNodeVerdictLabels is the default number of verdict labels kept per node.
+	DefaultMaxNodeVerdictLabels = 10
+
+	nodeVerdictQueueSize = 1000
+	nodeVerdictWorkers   = 4
+)
+
+// nodeVerdictRequest asks for the verdict labels of the images on the nodes.
+type nodeVerdictRequest struct {
+	namespace string
+	imageNFGs map[string][]string
+	nodeNames []string
+}
+
+// nodeVerdictQueue records node verdicts from a fixed set of background
+// workers, so that PreFilter never waits on, nor piles up, node patches.
+type nodeVerdictQueue struct {
+	queue chan nodeVerdictRequest
+}
+
+func newNodeVerdictQueue() *nodeVerdictQueue {
+	return &nodeVerdictQueue{queue: make(chan nodeVerdictRequest, nodeVerdictQueueSize)}
+}
+
+// enqueue queues the request, dropping it when the queue is full.
+func (q *nodeVerdictQueue) enqueue(req nodeVerdictRequest) {
+	if q == nil {
+		return
+	}
+	select {
+	case q.queue <- req:
+	default:
+		log.Printf("Node verdict queue is full, dropping the verdicts of %d nodes", len(req.nodeNames))
+	}
+}
+
+// run records the queued verdicts with nodeVerdictWorkers workers until ctx is done.
+func (q *nodeVerdictQueue) run(ctx context.Context, f *ImageCompatibilityPlugin) {
+	for range nodeVerdictWorkers {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case req := <-q.queue:
+					f.recordNodeVerdicts(ctx, req.namespace, req.imageNFGs, req.nodeNames)
+				}
+			}
+		}()
+	}
+}
+
+// nodeLocks hands out a mutex per node name.
+type nodeLocks struct {
+	mu    sync.Mutex
+	nodes map[string]*sync.Mutex
+}
+
+// lock locks the mutex of the node and returns its unlock function.
+func (l *nodeLocks) lock(nodeName string) func() {
+	l.mu.Lock()
+	if l.nodes == nil {
+		l.nodes = make(map[string]*sync.Mutex)
+	}
+	m, ok := l.nodes[nodeName]
+	if !ok {
+		m = &sync.Mutex{}
+		l.nodes[nodeName] = m
+	}
+	l.mu.Unlock()
+	m.Lock()
+	return m.Unlock
+}
+
+// nodeVerdictImage maps a verdict label back to the image it was computed for.
+type nodeVerdictImage struct {
+	Hash  string `json:"hash"`
+	Image string `json:"image"`
+}
+
+// nodeVerdictHash returns the label-safe hash identifying an image.
+func nodeVerdictHash(image string) string {
+	sum := sha256.Sum256([]byte(image))
+	return hex.EncodeToString(sum[:8])
+}
+
+// recordNodeVerdicts labels the given nodes with the verdict of each image:
+// compatible when the node is in the image's set of compatible nodes,
+// incompatible otherwise.
+func (f *ImageCompatibilityPlugin) recordNodeVerdicts(ctx context.Context, namespace string, imageNFGs map[string][]string, nodeNames []string) {
+	maxLabels := f.args.MaxNodeVerdictLabels
+	if maxLabels == 0 {
+		maxLabels = DefaultMaxNodeVerdictLabels
+	}
+
+	for image, nfgNames := range imageNFGs {
+		compatible := f.computeCompatibleNodes(ctx, namespace, nfgNames)
+		for _, nodeName := range nodeNames {
+			verdict := NodeVerdictIncompatible
+			if _, ok := compatible[nodeName]; ok {
+				verdict = NodeVerdictCompatible
+			}
+			f.patchNodeVerdict(ctx, nodeName, image, verdict, maxLabels)
+		}
+	}
+}
+
+// patchNodeVerdict sets the verdict label of image on the node. Patches of a
+// node are serialized so that concurrent workers do not overwrite each
+// other's images annotation, patches of different nodes run in parallel.
+func (f *ImageCompatibilityPlugin) patchNodeVerdict(ctx context.Context, nodeName, image, verdict string, maxLabels int) {
+	unlock := f.nodeVerdictLocks.lock(nodeName)
+	defer unlock()
+
+	node, err := f.handle.SharedInformerFactory().Core().V1().Nodes().Lister().Get(nodeName)
+	if err != nil {
+		log.Printf("Failed to get node %s for verdict labels: %v", nodeName, err)
+		return
+	}
+	patch, err := nodeVerdictPatch(node, image, verdict, maxLabels)
+	if err != nil {
+		log.Printf("Failed to build verdict patch for node %s: %v", nodeName, err)
+		return
+	}
+	if patch == nil {
+		return
+	}
+	if _, err := f.handle.ClientSet().CoreV1().Nodes().Patch(ctx, nodeName, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		log.Printf("Failed to patch verdict labels of node %s: %v", nodeName, err)
+	}
+}
+
+// nodeVerdictPatch returns the merge patch setting the verdict label of image
+// on node, removing the labels of the oldest images beyond maxLabels. It
+// returns nil when the node already carries the verdict.
+func nodeVerdictPatch(node *v1.Node, image, verdict string, maxLabels int) ([]byte, error) {
+	hash := nodeVerdictHash(image)
+	key := NodeVerdictLabelPrefix + hash
+
+	var images []nodeVerdictImage
+	if raw, ok := node.Annotations[NodeVerdictImagesAnnotation]; ok {
+		if err := json.Unmarshal([]byte(raw), &images); err != nil {
+			log.Printf("Ignoring invalid %s annotation on node %s: %v", NodeVerdictImagesAnnotation, node.Name, err)
+			images = nil
+		}
+	}
+
+	tracked := false
+	for _, img := range images {
+		if img.Hash == hash {
+			tracked = true
+			break
+		}
+	}
+	if tracked && node.Labels[key] == verdict {
+		return nil, nil
+	}
+
+	// Move the image to the end, it is now the most recent verdict
+	updated := make([]nodeVerdictImage, 0, len(images)+1)
+	for _, img := range images {
+		if img.Hash != hash {
+			updated = append(updated, img)
+		}
+	}
+	updated = append(updated, nodeVerdictImage{Hash: hash, Image: image})
+
+	labels := map[string]interface{}{key: verdict}
+	for len(updated) > maxLabels {
+		labels[NodeVerdictLabelPrefix+updated[0].Hash] = nil
+		updated = updated[1:]
+	}
+
+	annotation, err := json.Marshal(updated)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": labels,
+			"annotations": map[string]string{
+				NodeVerdictImagesAnnotation: string(annotation),
+			},
+		},
+	})
+}
@@ -0,0 +1,135 @@
+package compatibilityPlugin
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type nodeVerdictPatchBody struct {
+	Metadata struct {
+		Labels      map[string]*string `json:"labels"`
+		Annotations map[string]string  `json:"annotations"`
+	} `json:"metadata"`
+}
+
+func TestNodeVerdictPatch(t *testing.T) {
+	const image = "docker.io/library/app:v1"
+	key := NodeVerdictLabelPrefix + nodeVerdictHash(image)
+
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	raw, err := nodeVerdictPatch(node, image, NodeVerdictCompatible, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var patch nodeVerdictPatchBody
+	if err := json.Unmarshal(raw, &patch); err != nil {
+		t.Fatalf("invalid patch: %v", err)
+	}
+	if v := patch.Metadata.Labels[key]; v == nil || *v != NodeVerdictCompatible {
+		t.Errorf("expected label %s=%s, got %v", key, NodeVerdictCompatible, v)
+	}
+
+	// Applying the same verdict again is a no-op
+	node.Labels = map[string]string{key: NodeVerdictCompatible}
+	node.Annotations = map[string]string{NodeVerdictImagesAnnotation: patch.Metadata.Annotations[NodeVerdictImagesAnnotation]}
+	if raw, err := nodeVerdictPatch(node, image, NodeVerdictCompatible, 2); err != nil || raw != nil {
+		t.Errorf("expected no patch, got %s, %v", raw, err)
+	}
+}
+
+func TestNodeVerdictPatch_EvictsOldest(t *testing.T) {
+	images := []string{"registry.example.com/a:v1", "registry.example.com/b:v1", "registry.example.com/c:v1"}
+
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{}}}
+	var patch nodeVerdictPatchBody
+	for _, image := range images {
+		raw, err := nodeVerdictPatch(node, image, NodeVerdictIncompatible, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		patch = nodeVerdictPatchBody{}
+		if err := json.Unmarshal(raw, &patch); err != nil {
+			t.Fatalf("invalid patch: %v", err)
+		}
+		// Apply the patch to the node
+		for k, v := range patch.Metadata.Labels {
+			if v == nil {
+				delete(node.Labels, k)
+			} else {
+				node.Labels[k] = *v
+			}
+		}
+		node.Annotations = patch.Metadata.Annotations
+	}
+
+	oldest := NodeVerdictLabelPrefix + nodeVerdictHash(images[0])
+	if v, ok := patch.Metadata.Labels[oldest]; !ok || v != nil {
+		t.Errorf("expected oldest label %s to be removed by the patch", oldest)
+	}
+	if len(node.Labels) != 2 {
+		t.Errorf("expected 2 verdict labels, got %v", node.Labels)
+	}
+
+	var tracked []nodeVerdictImage
+	if err := json.Unmarshal([]byte(node.Annotations[NodeVerdictImagesAnnotation]), &tracked); err != nil {
+		t.Fatalf("invalid images annotation: %v", err)
+	}
+	if len(tracked) != 2 || tracked[0].Image != images[1] || tracked[1].Image != images[2] {
+		t.Errorf("unexpected tracked images %v", tracked)
+	}
+}
+
+func TestNodeVerdictQueueIsBounded(t *testing.T) {
+	q := newNodeVerdictQueue()
+	// Without workers the queue fills up and further requests are dropped
+	// rather than blocking PreFilter
+	for range nodeVerdictQueueSize + 10 {
+		q.enqueue(nodeVerdictRequest{nodeNames: []string{"node-1"}})
+	}
+	if len(q.queue) != nodeVerdictQueueSize {
+		t.Errorf("expected %d queued requests, got %d", nodeVerdictQueueSize, len(q.queue))
+	}
+
+	// Enqueueing without node verdict labels is a no-op
+	var disabled *nodeVerdictQueue
+	disabled.enqueue(nodeVerdictRequest{nodeNames: []string{"node-1"}})
+}
+
+func TestNodeLocks(t *testing.T) {
+	var locks nodeLocks
+	unlock := locks.lock("node-1")
+
+	// Another node is not held up by node-1
+	done := make(chan struct{})
+	go func() {
+		locks.lock("node-2")()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("lock of node-2 blocked on node-1")
+	}
+
+	// node-1 is locked until released
+	locked := make(chan struct{})
+	go func() {
+		locks.lock("node-1")()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatal("node-1 locked twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("node-1 not released")
+	}
+}
@@ -0,0 +1,110 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sclient "k8s.io/client-go/kubernetes"
+	compatv1alpha1 "sigs.k8s.io/node-feature-discovery/api/image-compatibility/v1alpha1"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// offlineArtifact is an entry of an offline artifact bundle: the
+// compatibility spec of an image, as attached to it in the registry.
+type offlineArtifact struct {
+	Image               string `json:"image"`
+	compatv1alpha1.Spec `json:",inline"`
+}
+
+// staticArtifactClient returns a compatibility spec known in advance.
+type staticArtifactClient struct {
+	spec *compatv1alpha1.Spec
+}
+
+func (c *staticArtifactClient) FetchCompatibilitySpec(context.Context) (*compatv1alpha1.Spec, error) {
+	return c.spec, nil
+}
+
+// parseOfflineArtifacts parses the bundle entries, keyed by their source for
+// error reporting, into specs by image.
+func parseOfflineArtifacts(entries map[string][]byte, normalize bool) (map[string]*compatv1alpha1.Spec, error) {
+	specs := make(map[string]*compatv1alpha1.Spec, len(entries))
+	for source, data := range entries {
+		artifact := &offlineArtifact{}
+		if err := sigsyaml.Unmarshal(data, artifact); err != nil {
+			return nil, fmt.Errorf("invalid offline artifact %s: %w", source, err)
+		}
+		if artifact.Image == "" {
+			return nil, fmt.Errorf("offline artifact %s has no image", source)
+		}
+		image := artifact.Image
+		if normalize {
+			normalized, err := normalizeImageReference(image)
+			if err != nil {
+				return nil, fmt.Errorf("offline artifact %s has an invalid image: %w", source, err)
+			}
+			image = normalized
+		}
+		specs[image] = &artifact.Spec
+	}
+	return specs, nil
+}
+
+// loadOfflineArtifacts reads the offline artifact bundle configured by args
+// from OfflineArtifactDir and OfflineArtifactConfigMap.
+func loadOfflineArtifacts(ctx context.Context, kubeClient k8sclient.Interface, args ImageCompatibilityPluginArgs) (map[string]*compatv1alpha1.Spec, error) {
+	entries := make(map[string][]byte)
+	if args.OfflineArtifactDir != "" {
+		for _, pattern := range []string{"*.yaml", "*.yml", "*.json"} {
+			paths, err := filepath.Glob(filepath.Join(args.OfflineArtifactDir, pattern))
+			if err != nil {
+				return nil, err
+			}
+			for _, path := range paths {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read offline artifact: %w", err)
+				}
+				entries[path] = data
+			}
+		}
+	}
+	if args.OfflineArtifactConfigMap != "" {
+		nn, err := parseNamespacedName(args.OfflineArtifactConfigMap)
+		if err != nil {
+			return nil, err
+		}
+		cm, err := kubeClient.CoreV1().ConfigMaps(nn.Namespace).Get(ctx, nn.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get offline artifact ConfigMap %s: %w", nn, err)
+		}
+		for key, value := range cm.Data {
+			entries[nn.String()+"/"+key] = []byte(value)
+		}
+	}
+	return parseOfflineArtifacts(entries, args.NormalizeImageReferences)
+}
+
+// lookupOfflineArtifact returns a client serving the spec of the image from
+// the offline artifact bundle, or nil when the bundle does not have it and
+// the registry has to be used.
+func lookupOfflineArtifact(ctx context.Context, kubeClient k8sclient.Interface, imageName string, args ImageCompatibilityPluginArgs) *staticArtifactClient {
+	if args.OfflineArtifactDir == "" && args.OfflineArtifactConfigMap == "" {
+		return nil
+	}
+	specs, err := loadOfflineArtifacts(ctx, kubeClient, args)
+	if err != nil {
+		log.Printf("Failed to load offline artifacts, using the registry for image %s: %v", imageName, err)
+		return nil
+	}
+	spec, ok := specs[imageName]
+	if !ok {
+		return nil
+	}
+	log.Printf("Using the offline compatibility artifact of image %s", imageName)
+	return &staticArtifactClient{spec: spec}
+}
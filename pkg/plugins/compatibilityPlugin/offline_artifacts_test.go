@@ -0,0 +1,66 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const offlineArtifactTemplate = `
+image: %s
+version: v1alpha1
+compatibilities:
+- description: kernel
+  rules:
+  - name: kernel
+    matchFeatures:
+    - feature: kernel.version
+      matchExpressions:
+        major: {op: Gt, value: ["5"]}
+`
+
+func TestLookupOfflineArtifact(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.yaml"), []byte(fmt.Sprintf(offlineArtifactTemplate, "app:v1")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	kubeClient := fake.NewSimpleClientset(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "custom-scheduler", Name: "artifacts"},
+		Data:       map[string]string{"base": fmt.Sprintf(offlineArtifactTemplate, "registry.example.com/base:v1")},
+	})
+	args := ImageCompatibilityPluginArgs{
+		OfflineArtifactDir:       dir,
+		OfflineArtifactConfigMap: "custom-scheduler/artifacts",
+		NormalizeImageReferences: true,
+	}
+
+	for _, image := range []string{"docker.io/library/app:v1", "registry.example.com/base:v1"} {
+		client := lookupOfflineArtifact(context.Background(), kubeClient, image, args)
+		if client == nil {
+			t.Fatalf("expected offline artifact for %s", image)
+		}
+		groups, err := NewFeatureGroupManagement(client).TransferFromArtifact(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(groups) != 1 || groups[0].Spec.Rules[0].Name != "kernel" {
+			t.Errorf("unexpected groups for %s: %+v", image, groups)
+		}
+	}
+
+	if client := lookupOfflineArtifact(context.Background(), kubeClient, "docker.io/library/other:v1", args); client != nil {
+		t.Errorf("expected no offline artifact for an unknown image")
+	}
+}
+
+func TestParseOfflineArtifactsWithoutImage(t *testing.T) {
+	if _, err := parseOfflineArtifacts(map[string][]byte{"bad.yaml": []byte("version: v1alpha1")}, false); err == nil {
+		t.Errorf("expected error for an artifact without image")
+	}
+}
@@ -0,0 +1,56 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	nfdclientset "sigs.k8s.io/node-feature-discovery/api/generated/clientset/versioned"
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
+)
+
+// NFGSpecHashAnnotation holds the hash of the compatibility set a
+// NodeFeatureGroup was created from, see compatibilitySetHash.
+const NFGSpecHashAnnotation = "image-compat.scheduler/spec-hash"
+
+// compatibilitySetHash returns a hash of the rules, weight and tag of the
+// compatibility set the group is created from. Groups with the same hash
+// match the same nodes, so an existing group can stand in for a new one.
+func compatibilitySetHash(nfg *nfdv1alpha1.NodeFeatureGroup) (string, error) {
+	data, err := json.Marshal(struct {
+		Rules  []nfdv1alpha1.GroupRule `json:"rules"`
+		Weight string                  `json:"weight,omitempty"`
+		Tag    string                  `json:"tag,omitempty"`
+	}{nfg.Spec.Rules, nfg.Annotations[NFGWeightAnnotation], nfg.Annotations[NFGTagAnnotation]})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// reusableNFGs returns the groups among nfgNames that still exist, keyed by
+// the hash of their compatibility set. These are the groups of an image's
+// expired cache entry: when the image is evaluated again, the compatibility
+// sets that did not change reuse them, whose status nfd-master keeps up to
+// date, and only the changed sets get new groups.
+func reusableNFGs(ctx context.Context, cli nfdclientset.Interface, namespace string, nfgNames []string) map[string]nfdv1alpha1.NodeFeatureGroup {
+	if len(nfgNames) == 0 {
+		return nil
+	}
+	reusable := make(map[string]nfdv1alpha1.NodeFeatureGroup, len(nfgNames))
+	for _, name := range nfgNames {
+		nfg, err := cli.NfdV1alpha1().NodeFeatureGroups(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			log.Printf("NFG %s cannot be reused: %v", name, err)
+			continue
+		}
+		if hash := nfg.Annotations[NFGSpecHashAnnotation]; hash != "" {
+			reusable[hash] = *nfg
+		}
+	}
+	return reusable
+}
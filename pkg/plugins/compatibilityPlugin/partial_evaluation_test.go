@@ -0,0 +1,130 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	nfdfake "sigs.k8s.io/node-feature-discovery/api/generated/clientset/versioned/fake"
+	compatv1alpha1 "sigs.k8s.io/node-feature-discovery/api/image-compatibility/v1alpha1"
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
+)
+
+func TestCreateNodeFeatureGroupsReusesUnchangedSets(t *testing.T) {
+	// The fake clientset ignores GenerateName, name the groups in the reactor
+	nfdCli := nfdfake.NewSimpleClientset()
+	var creates atomic.Int32
+	nfdCli.PrependReactor("create", "nodefeaturegroups", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		nfg := action.(k8stesting.CreateAction).GetObject().(*nfdv1alpha1.NodeFeatureGroup)
+		nfg.Name = nfg.GenerateName + string(rune('a'+creates.Add(1)))
+		return false, nil, nil
+	})
+	ctx := context.Background()
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+
+	spec := &compatv1alpha1.Spec{Version: "v1alpha1", Compatibilties: []compatv1alpha1.Compatibility{
+		{Tag: "cpu", Rules: []nfdv1alpha1.GroupRule{{Name: "cpu"}}},
+		{Tag: "kernel", Rules: []nfdv1alpha1.GroupRule{{Name: "kernel"}}},
+	}}
+	first, err := NewFeatureGroupManagement(&MockArtifactClient{spec: spec}).CreateNodeFeatureGroupsFromArtifact(ctx, nfdCli, pod, "nfd")
+	if err != nil {
+		t.Fatalf("CreateNodeFeatureGroupsFromArtifact: %v", err)
+	}
+
+	// Change the rules of the kernel set only
+	spec.Compatibilties[1].Rules = []nfdv1alpha1.GroupRule{{Name: "kernel-6"}}
+	reusable := reusableNFGs(ctx, nfdCli, "nfd", []string{first[0].Name, first[1].Name})
+	second, err := NewFeatureGroupManagement(&MockArtifactClient{spec: spec}).
+		WithReusableGroups(reusable).
+		CreateNodeFeatureGroupsFromArtifact(ctx, nfdCli, pod, "nfd")
+	if err != nil {
+		t.Fatalf("CreateNodeFeatureGroupsFromArtifact: %v", err)
+	}
+
+	if creates.Load() != 3 {
+		t.Errorf("expected only the changed set to get a new group, got %d creates", creates.Load())
+	}
+	if len(second) != 2 || second[0].Name != first[0].Name {
+		t.Fatalf("expected the unchanged cpu set to reuse %s, got %v", first[0].Name, second)
+	}
+	if second[1].Name == first[1].Name {
+		t.Errorf("expected the changed kernel set to get a new group, got %s", second[1].Name)
+	}
+}
+
+func TestReusedNFGsFollowTheLivePod(t *testing.T) {
+	// The fake clientset ignores GenerateName and cannot list
+	// NodeFeatureGroups, name and list them in reactors
+	nfdCli := nfdfake.NewSimpleClientset()
+	var mu sync.Mutex
+	var names []string
+	nfdCli.PrependReactor("create", "nodefeaturegroups", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		nfg := action.(k8stesting.CreateAction).GetObject().(*nfdv1alpha1.NodeFeatureGroup)
+		mu.Lock()
+		defer mu.Unlock()
+		nfg.Name = nfg.GenerateName + string(rune('a'+len(names)))
+		names = append(names, nfg.Name)
+		return false, nil, nil
+	})
+	nfdCli.PrependReactor("list", "nodefeaturegroups", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		selector := action.(k8stesting.ListAction).GetListRestrictions().Labels
+		list := &nfdv1alpha1.NodeFeatureGroupList{}
+		for _, name := range names {
+			obj, err := nfdCli.Tracker().Get(nfdv1alpha1.SchemeGroupVersion.WithResource("nodefeaturegroups"), "nfd", name)
+			if err != nil {
+				continue
+			}
+			if nfg := obj.(*nfdv1alpha1.NodeFeatureGroup); selector.Matches(labels.Set(nfg.Labels)) {
+				list.Items = append(list.Items, *nfg)
+			}
+		}
+		return true, list, nil
+	})
+	ctx := context.Background()
+	first := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "first", Namespace: "default", UID: "uid-first"}}
+	second := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "second", Namespace: "default", UID: "uid-second"}}
+
+	spec := &compatv1alpha1.Spec{Version: "v1alpha1", Compatibilties: []compatv1alpha1.Compatibility{
+		{Tag: "cpu", Rules: []nfdv1alpha1.GroupRule{{Name: "cpu"}}},
+	}}
+	created, err := NewFeatureGroupManagement(&MockArtifactClient{spec: spec}).CreateNodeFeatureGroupsFromArtifact(ctx, nfdCli, first, "nfd")
+	if err != nil {
+		t.Fatalf("CreateNodeFeatureGroupsFromArtifact: %v", err)
+	}
+	reused, err := NewFeatureGroupManagement(&MockArtifactClient{spec: spec}).
+		WithReusableGroups(reusableNFGs(ctx, nfdCli, "nfd", []string{created[0].Name})).
+		CreateNodeFeatureGroupsFromArtifact(ctx, nfdCli, second, "nfd")
+	if err != nil {
+		t.Fatalf("CreateNodeFeatureGroupsFromArtifact: %v", err)
+	}
+	if reused[0].Name != created[0].Name || reused[0].Labels["pod-uid"] != "uid-second" {
+		t.Fatalf("expected %s to be reused for the second pod, got %+v", created[0].Name, reused[0].ObjectMeta)
+	}
+
+	// Cached without expiry, as with the default cacheTTL
+	plugin := &ImageCompatibilityPlugin{imageToNFGCache: newNFGCache()}
+	plugin.updateCacheForImage("docker.io/library/app:v1", []string{reused[0].Name})
+	exists := func() bool {
+		_, err := nfdCli.NfdV1alpha1().NodeFeatureGroups("nfd").Get(ctx, reused[0].Name, metav1.GetOptions{})
+		return err == nil
+	}
+
+	// The first pod is gone, the second still uses the group
+	plugin.deleteOrphanedNFGs(ctx, fake.NewSimpleClientset(second), nfdCli, "nfd")
+	if !exists() {
+		t.Fatalf("expected %s to be kept while the second pod lives", reused[0].Name)
+	}
+
+	// No live pod uses the group anymore
+	plugin.deleteOrphanedNFGs(ctx, fake.NewSimpleClientset(), nfdCli, "nfd")
+	if exists() {
+		t.Errorf("expected %s to be deleted once no pod uses it", reused[0].Name)
+	}
+}
@@ -0,0 +1,143 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	oras "oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+// Media types of multi-platform image indexes.
+const (
+	ociImageIndexMediaType      = "application/vnd.oci.image.index.v1+json"
+	dockerManifestListMediaType = "application/vnd.docker.distribution.manifest.list.v2+json"
+)
+
+// ArchitectureCompatibility is the compatibility of the image variant of an
+// architecture with the nodes of that architecture.
+type ArchitectureCompatibility struct {
+	Architecture string `json:"architecture"`
+	// Image is the evaluated variant, empty when the image has no variant for
+	// the architecture and none of its nodes are compatible
+	Image             string   `json:"image,omitempty"`
+	CompatibleNodes   []string `json:"compatibleNodes"`
+	IncompatibleNodes []string `json:"incompatibleNodes"`
+}
+
+// imageIndex is the part of an image index listing the platform variants.
+type imageIndex struct {
+	Manifests []struct {
+		Digest   string `json:"digest"`
+		Platform *struct {
+			OS           string `json:"os"`
+			Architecture string `json:"architecture"`
+		} `json:"platform,omitempty"`
+	} `json:"manifests"`
+}
+
+// platformVariants returns the digest references of the linux variants of
+// the image index by architecture, the first variant of an architecture
+// winning.
+func platformVariants(ref registry.Reference, content []byte) (map[string]string, error) {
+	var index imageIndex
+	if err := json.Unmarshal(content, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse image index: %w", err)
+	}
+
+	variants := make(map[string]string)
+	for _, m := range index.Manifests {
+		if m.Platform == nil || m.Platform.OS != "linux" {
+			continue
+		}
+		if _, found := variants[m.Platform.Architecture]; found {
+			continue
+		}
+		variant := ref
+		variant.Reference = m.Digest
+		variants[m.Platform.Architecture] = variant.String()
+	}
+	return variants, nil
+}
+
+// fetchPlatformVariants returns the variants of the image by architecture,
+// or nil if the image is not a multi-platform index.
+func (s *Simulator) fetchPlatformVariants(ctx context.Context, image string) (map[string]string, error) {
+	ref, err := registry.ParseReference(image)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image reference %s: %w", image, err)
+	}
+	cred, err := lookupRegistryCredential(ctx, s.KubeClient, ref.Registry, s.Args)
+	if err != nil {
+		return nil, err
+	}
+	repo, err := remote.NewRepository(ref.String())
+	if err != nil {
+		return nil, err
+	}
+	repo.Client = newRegistryClient(ref.Registry, cred)
+	repo.PlainHTTP = s.Args.PlainHttp
+
+	desc, content, err := oras.FetchBytes(ctx, repo, ref.ReferenceOrDefault(), oras.DefaultFetchBytesOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest of image %s: %w", image, err)
+	}
+	if desc.MediaType != ociImageIndexMediaType && desc.MediaType != dockerManifestListMediaType {
+		return nil, nil
+	}
+	return platformVariants(ref, content)
+}
+
+// SimulateArchitectures evaluates, for every architecture of the selected
+// nodes, the image variant of that architecture against its nodes, so that
+// e.g. a DaemonSet image can be checked across the cluster at once. Images
+// that are not multi-platform are evaluated as-is for every architecture.
+func (s *Simulator) SimulateArchitectures(ctx context.Context, image string) ([]ArchitectureCompatibility, error) {
+	nodes, nodeFeatures, err := s.listNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodesByArch := make(map[string][]v1.Node)
+	for _, node := range nodes {
+		arch := node.Labels[v1.LabelArchStable]
+		nodesByArch[arch] = append(nodesByArch[arch], node)
+	}
+
+	variants, err := s.fetchPlatformVariants(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ArchitectureCompatibility, 0, len(nodesByArch))
+	for arch, archNodes := range nodesByArch {
+		result := ArchitectureCompatibility{Architecture: arch, Image: image}
+		if variants != nil {
+			result.Image = variants[arch]
+		}
+		if result.Image == "" {
+			// No variant can run on the nodes of this architecture
+			log.Printf("Image %s has no variant for architecture %q", image, arch)
+			for _, node := range archNodes {
+				result.IncompatibleNodes = append(result.IncompatibleNodes, node.Name)
+			}
+			slices.Sort(result.IncompatibleNodes)
+			results = append(results, result)
+			continue
+		}
+
+		groups, err := s.compatibilityGroups(ctx, result.Image)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate image %s for architecture %q: %w", result.Image, arch, err)
+		}
+		result.CompatibleNodes, result.IncompatibleNodes = evaluateNodes(archNodes, nodeFeatures, groups, s.decision())
+		results = append(results, result)
+	}
+	slices.SortFunc(results, func(a, b ArchitectureCompatibility) int { return strings.Compare(a.Architecture, b.Architecture) })
+	return results, nil
+}
@@ -0,0 +1,37 @@
+package compatibilityPlugin
+
+import (
+	"reflect"
+	"testing"
+
+	"oras.land/oras-go/v2/registry"
+)
+
+func TestPlatformVariants(t *testing.T) {
+	index := `{
+  "mediaType": "application/vnd.oci.image.index.v1+json",
+  "manifests": [
+    {"digest": "sha256:1111111111111111111111111111111111111111111111111111111111111111", "platform": {"os": "linux", "architecture": "amd64"}},
+    {"digest": "sha256:2222222222222222222222222222222222222222222222222222222222222222", "platform": {"os": "linux", "architecture": "arm64"}},
+    {"digest": "sha256:3333333333333333333333333333333333333333333333333333333333333333", "platform": {"os": "linux", "architecture": "arm64", "variant": "v8"}},
+    {"digest": "sha256:4444444444444444444444444444444444444444444444444444444444444444", "platform": {"os": "windows", "architecture": "amd64"}},
+    {"digest": "sha256:5555555555555555555555555555555555555555555555555555555555555555"}
+  ]
+}`
+	ref, err := registry.ParseReference("registry.example.com/app:v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := platformVariants(ref, []byte(index))
+	if err != nil {
+		t.Fatalf("platformVariants() failed: %v", err)
+	}
+	want := map[string]string{
+		"amd64": "registry.example.com/app@sha256:1111111111111111111111111111111111111111111111111111111111111111",
+		"arm64": "registry.example.com/app@sha256:2222222222222222222222222222222222222222222222222222222222222222",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("platformVariants() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,66 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	k8sclient "k8s.io/client-go/kubernetes"
+	compatv1alpha1 "sigs.k8s.io/node-feature-discovery/api/image-compatibility/v1alpha1"
+	artifactcli "sigs.k8s.io/node-feature-discovery/pkg/client-nfd/compat/artifact-client"
+)
+
+// originImage returns the image of the origin registry of an image pulled
+// from one of the pull-through caches, the first matching cache winning.
+func originImage(image string, caches []PullThroughCache) (string, bool) {
+	for _, c := range caches {
+		if rest, found := strings.CutPrefix(image, c.Cache); found {
+			return c.Origin + rest, true
+		}
+	}
+	return "", false
+}
+
+// newCachedImageArtifactClient creates the artifact client of the image,
+// falling back to its origin registry when the image is pulled from a
+// pull-through cache. Both registries use their own pull secret.
+func newCachedImageArtifactClient(ctx context.Context, kubeClient k8sclient.Interface, imageName string, args ImageCompatibilityPluginArgs) (artifactcli.ArtifactClient, error) {
+	client, err := newImageArtifactClient(ctx, kubeClient, imageName, args)
+	if err != nil {
+		return nil, err
+	}
+	origin, found := originImage(imageName, args.PullThroughCaches)
+	if !found {
+		return client, nil
+	}
+	originClient, err := newImageArtifactClient(ctx, kubeClient, origin, args)
+	if err != nil {
+		return nil, err
+	}
+	return &cacheFallbackArtifactClient{cache: client, origin: originClient, image: imageName, originImage: origin}, nil
+}
+
+// cacheFallbackArtifactClient fetches the compatibility artifact from a
+// pull-through cache, falling back to the origin registry when the cache does
+// not have the image or its artifact.
+type cacheFallbackArtifactClient struct {
+	cache       artifactcli.ArtifactClient
+	origin      artifactcli.ArtifactClient
+	image       string
+	originImage string
+}
+
+func (c *cacheFallbackArtifactClient) FetchCompatibilitySpec(ctx context.Context) (*compatv1alpha1.Spec, error) {
+	spec, err := c.cache.FetchCompatibilitySpec(ctx)
+	switch {
+	case err == nil && spec != nil:
+		return spec, nil
+	case err == nil:
+		log.Printf("Pull-through cache has no compatibility artifact for image %s, using %s", c.image, c.originImage)
+	case isNoCompatibilityArtifactError(err) || isImageNotFoundError(err):
+		log.Printf("Pull-through cache has no compatibility artifact for image %s, using %s: %v", c.image, c.originImage, err)
+	default:
+		return nil, err
+	}
+	return c.origin.FetchCompatibilitySpec(ctx)
+}
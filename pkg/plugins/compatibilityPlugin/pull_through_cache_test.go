@@ -0,0 +1,71 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	compatv1alpha1 "sigs.k8s.io/node-feature-discovery/api/image-compatibility/v1alpha1"
+)
+
+func TestOriginImage(t *testing.T) {
+	caches := []PullThroughCache{{Cache: "cache.example.com/dockerhub/", Origin: "docker.io/"}}
+
+	if got, found := originImage("cache.example.com/dockerhub/library/app:v1", caches); !found || got != "docker.io/library/app:v1" {
+		t.Errorf("originImage() = %q, %v, want docker.io/library/app:v1", got, found)
+	}
+	if _, found := originImage("registry.example.com/app:v1", caches); found {
+		t.Errorf("expected an image not pulled from a cache to have no origin")
+	}
+}
+
+func TestCacheFallbackArtifactClient(t *testing.T) {
+	originSpec := &compatv1alpha1.Spec{Version: "v1alpha1"}
+	tests := []struct {
+		name        string
+		cache       *MockArtifactClient
+		want        *compatv1alpha1.Spec
+		wantErr     bool
+		originCalls int32
+	}{
+		{
+			name:        "cache misses the artifact",
+			cache:       &MockArtifactClient{err: errors.New("compatibility artifact not found")},
+			want:        originSpec,
+			originCalls: 1,
+		},
+		{
+			name:        "cache does not list referrers",
+			cache:       &MockArtifactClient{},
+			want:        originSpec,
+			originCalls: 1,
+		},
+		{
+			name:  "cache hits",
+			cache: &MockArtifactClient{spec: &compatv1alpha1.Spec{Version: "cached"}},
+			want:  &compatv1alpha1.Spec{Version: "cached"},
+		},
+		{
+			name:    "cache fails",
+			cache:   &MockArtifactClient{err: errors.New("connection refused")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			origin := &MockArtifactClient{spec: originSpec}
+			client := &cacheFallbackArtifactClient{cache: tt.cache, origin: origin, image: "cache/app:v1", originImage: "origin/app:v1"}
+
+			got, err := client.FetchCompatibilitySpec(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchCompatibilitySpec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Version != tt.want.Version {
+				t.Errorf("FetchCompatibilitySpec() = %v, want %v", got.Version, tt.want.Version)
+			}
+			if calls := origin.calls.Load(); calls != tt.originCalls {
+				t.Errorf("expected %d origin fetches, got %d", tt.originCalls, calls)
+			}
+		})
+	}
+}
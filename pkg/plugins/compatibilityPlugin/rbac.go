@@ -0,0 +1,91 @@
+package compatibilityPlugin
+
+import (
+	"slices"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// PolicyRules returns the RBAC rules the plugin needs on top of those of
+// kube-scheduler, for the features enabled by args.
+func PolicyRules(args ImageCompatibilityPluginArgs) []rbacv1.PolicyRule {
+	rules := []rbacv1.PolicyRule{
+		// NodeFeatureGroups are created per image and watched for requeueing
+		{
+			APIGroups: []string{"nfd.k8s-sigs.io"},
+			Resources: []string{"nodefeaturegroups"},
+			Verbs:     []string{"get", "list", "watch", "create", "update", "patch", "delete"},
+		},
+		// NodeFeatures are evaluated in-process by the simulation
+		{
+			APIGroups: []string{"nfd.k8s-sigs.io"},
+			Resources: []string{"nodefeatures"},
+			Verbs:     []string{"get", "list", "watch"},
+		},
+	}
+
+	switch {
+	case args.CustomFeaturesConfigMap != "":
+		// The custom features ConfigMap is watched by an informer
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups: []string{""},
+			Resources: []string{"configmaps"},
+			Verbs:     []string{"get", "list", "watch"},
+		})
+	case args.InstanceTypeFeaturesConfigMap != "" || args.OfflineArtifactConfigMap != "":
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups: []string{""},
+			Resources: []string{"configmaps"},
+			Verbs:     []string{"get"},
+		})
+	}
+
+	if args.ResultOwners {
+		// ReplicaSets are already watched by kube-scheduler
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups: []string{"batch"},
+			Resources: []string{"jobs"},
+			Verbs:     []string{"get", "list", "watch"},
+		})
+	}
+
+	if args.NodeVerdictLabels {
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups: []string{""},
+			Resources: []string{"nodes"},
+			Verbs:     []string{"patch"},
+		})
+	}
+	return rules
+}
+
+// SecretPolicyRules returns, by namespace, the rule to get the Secrets the
+// plugin reads, registry pull secrets and the result webhook auth secret,
+// limited to their names. Secrets are never granted cluster-wide.
+func SecretPolicyRules(args ImageCompatibilityPluginArgs) map[string]rbacv1.PolicyRule {
+	refs := make([]string, 0, len(args.RegistryPullSecrets)+1)
+	for _, ref := range args.RegistryPullSecrets {
+		refs = append(refs, ref)
+	}
+	if args.ResultWebhookAuthSecret != "" {
+		refs = append(refs, args.ResultWebhookAuthSecret)
+	}
+
+	rules := make(map[string]rbacv1.PolicyRule)
+	for _, ref := range refs {
+		nn, err := parseNamespacedName(ref)
+		if err != nil {
+			continue
+		}
+		rule, ok := rules[nn.Namespace]
+		if !ok {
+			rule = rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}}
+		}
+		if !slices.Contains(rule.ResourceNames, nn.Name) {
+			rule.ResourceNames = append(rule.ResourceNames, nn.Name)
+			slices.Sort(rule.ResourceNames)
+		}
+		rules[nn.Namespace] = rule
+	}
+	return rules
+}
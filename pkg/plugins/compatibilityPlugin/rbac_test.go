@@ -0,0 +1,60 @@
+package compatibilityPlugin
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPolicyRules(t *testing.T) {
+	hasRule := func(args ImageCompatibilityPluginArgs, resource, verb string) bool {
+		for _, rule := range PolicyRules(args) {
+			if slices.Contains(rule.Resources, resource) && slices.Contains(rule.Verbs, verb) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !hasRule(ImageCompatibilityPluginArgs{}, "nodefeaturegroups", "create") {
+		t.Errorf("expected nodefeaturegroups create to always be required")
+	}
+	if hasRule(ImageCompatibilityPluginArgs{}, "secrets", "get") || hasRule(ImageCompatibilityPluginArgs{}, "nodes", "patch") {
+		t.Errorf("expected no secrets or nodes rules by default")
+	}
+	if hasRule(ImageCompatibilityPluginArgs{RegistryPullSecrets: map[string]string{"r": "ns/s"}}, "secrets", "get") {
+		t.Errorf("expected secrets get to be namespaced, not cluster-wide")
+	}
+	if !hasRule(ImageCompatibilityPluginArgs{NodeVerdictLabels: true}, "nodes", "patch") {
+		t.Errorf("expected nodes patch with node verdict labels")
+	}
+	if !hasRule(ImageCompatibilityPluginArgs{ResultOwners: true}, "jobs", "watch") {
+		t.Errorf("expected jobs watch with result owners")
+	}
+	if !hasRule(ImageCompatibilityPluginArgs{CustomFeaturesConfigMap: "ns/cm"}, "configmaps", "watch") {
+		t.Errorf("expected configmaps watch with custom features")
+	}
+}
+
+func TestSecretPolicyRules(t *testing.T) {
+	if rules := SecretPolicyRules(ImageCompatibilityPluginArgs{}); len(rules) != 0 {
+		t.Errorf("expected no secrets rules by default, got %v", rules)
+	}
+
+	rules := SecretPolicyRules(ImageCompatibilityPluginArgs{
+		RegistryPullSecrets: map[string]string{
+			"registry.example.com": "registry-creds/shared-pull",
+			"docker.io":            "registry-creds/shared-pull",
+			"quay.io":              "registry-creds/quay-pull",
+		},
+		ResultWebhookAuthSecret: "custom-scheduler/webhook-auth",
+	})
+	if len(rules) != 2 {
+		t.Fatalf("expected rules for 2 namespaces, got %v", rules)
+	}
+	if got, want := rules["registry-creds"].ResourceNames, []string{"quay-pull", "shared-pull"}; !slices.Equal(got, want) {
+		t.Errorf("registry-creds secrets = %v, want %v", got, want)
+	}
+	if got, want := rules["custom-scheduler"].ResourceNames, []string{"webhook-auth"}; !slices.Equal(got, want) {
+		t.Errorf("custom-scheduler secrets = %v, want %v", got, want)
+	}
+}
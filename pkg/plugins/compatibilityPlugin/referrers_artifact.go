@@ -0,0 +1,85 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	oras "oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote"
+	compatv1alpha1 "sigs.k8s.io/node-feature-discovery/api/image-compatibility/v1alpha1"
+	artifactcli "sigs.k8s.io/node-feature-discovery/pkg/client-nfd/compat/artifact-client"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// referrersArtifactClient fetches the newest compatibility artifact attached
+// to the image as a referrer, like artifactcli.Client but with a registry
+// client of our own, so that identity tokens are used as refresh tokens.
+type referrersArtifactClient struct {
+	ref       registry.Reference
+	client    remote.Client
+	plainHTTP bool
+}
+
+// newReferrersArtifactClient creates a referrersArtifactClient authenticated
+// with the credential, if any.
+func newReferrersArtifactClient(ref registry.Reference, cred *registryCredential, plainHTTP bool) *referrersArtifactClient {
+	return &referrersArtifactClient{ref: ref, client: newRegistryClient(ref.Registry, cred), plainHTTP: plainHTTP}
+}
+
+func (c *referrersArtifactClient) FetchCompatibilitySpec(ctx context.Context) (*compatv1alpha1.Spec, error) {
+	repo, err := remote.NewRepository(c.ref.String())
+	if err != nil {
+		return nil, err
+	}
+	repo.Client = c.client
+	repo.PlainHTTP = c.plainHTTP
+
+	target, err := oras.Resolve(ctx, repo, c.ref.Reference, oras.DefaultResolveOptions)
+	if err != nil {
+		return nil, err
+	}
+	descs, err := registry.Referrers(ctx, repo, target, compatv1alpha1.ArtifactType)
+	if err != nil {
+		// As in artifactcli.Client, a registry failing to list the referrers
+		// leaves the image without requirements
+		return nil, nil
+	}
+	if len(descs) < 1 {
+		return nil, fmt.Errorf("compatibility artifact not found")
+	}
+	sort.SliceStable(descs, func(i, j int) bool {
+		ti, _ := time.Parse(time.RFC3339, descs[i].Annotations[artifactcli.ArtifactCreationTimestampKey])
+		tj, _ := time.Parse(time.RFC3339, descs[j].Annotations[artifactcli.ArtifactCreationTimestampKey])
+		return ti.Before(tj)
+	})
+
+	_, content, err := oras.FetchBytes(ctx, repo.Manifests(), descs[len(descs)-1].Digest.String(), oras.DefaultFetchBytesOptions)
+	if err != nil {
+		return nil, err
+	}
+	var manifest struct {
+		Layers []struct {
+			Digest string `json:"digest"`
+		} `json:"layers"`
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, err
+	}
+	if len(manifest.Layers) < 1 {
+		return nil, fmt.Errorf("compatibility layer not found")
+	}
+
+	_, specRaw, err := oras.FetchBytes(ctx, repo.Blobs(), manifest.Layers[0].Digest, oras.DefaultFetchBytesOptions)
+	if err != nil {
+		return nil, err
+	}
+	spec := &compatv1alpha1.Spec{}
+	if err := sigsyaml.Unmarshal(specRaw, spec); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
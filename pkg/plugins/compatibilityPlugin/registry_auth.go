@@ -0,0 +1,164 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sclient "k8s.io/client-go/kubernetes"
+)
+
+// registryCredential holds the credential used to fetch compatibility artifacts.
+type registryCredential struct {
+	Username      string
+	Password      string
+	IdentityToken string
+}
+
+// dockerConfigEntry is an entry of the auths section of a docker config.
+type dockerConfigEntry struct {
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	Auth          string `json:"auth,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+}
+
+// dockerConfigJSON is the content of a kubernetes.io/dockerconfigjson secret.
+type dockerConfigJSON struct {
+	Auths map[string]dockerConfigEntry `json:"auths"`
+}
+
+// pullSecretTTL is how long a pull secret is reused before it is read again,
+// so that artifact fetches do not each get the Secret and rotated
+// credentials are still picked up.
+const pullSecretTTL = time.Minute
+
+// pullSecretKey identifies a cached pull secret of a cluster.
+type pullSecretKey struct {
+	client k8sclient.Interface
+	secret types.NamespacedName
+}
+
+type cachedPullSecret struct {
+	secret    *v1.Secret
+	fetchedAt time.Time
+}
+
+// pullSecrets caches the pull secrets read by lookupRegistryCredential.
+var pullSecrets = struct {
+	mu      sync.Mutex
+	secrets map[pullSecretKey]cachedPullSecret
+}{secrets: make(map[pullSecretKey]cachedPullSecret)}
+
+// getPullSecret returns the pull secret, read at most once per pullSecretTTL.
+func getPullSecret(ctx context.Context, kubeClient k8sclient.Interface, nn types.NamespacedName) (*v1.Secret, error) {
+	key := pullSecretKey{client: kubeClient, secret: nn}
+	pullSecrets.mu.Lock()
+	cached, ok := pullSecrets.secrets[key]
+	pullSecrets.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < pullSecretTTL {
+		return cached.secret, nil
+	}
+
+	secret, err := kubeClient.CoreV1().Secrets(nn.Namespace).Get(ctx, nn.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pullSecrets.mu.Lock()
+	pullSecrets.secrets[key] = cachedPullSecret{secret: secret, fetchedAt: time.Now()}
+	pullSecrets.mu.Unlock()
+	return secret, nil
+}
+
+// parseNamespacedName parses a "namespace/name" object reference.
+func parseNamespacedName(ref string) (types.NamespacedName, error) {
+	namespace, name, found := strings.Cut(ref, "/")
+	if !found || namespace == "" || name == "" || strings.Contains(name, "/") {
+		return types.NamespacedName{}, fmt.Errorf("invalid reference %q, expected namespace/name", ref)
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}, nil
+}
+
+// lookupRegistryCredential returns the credential for the registry host from
+// the pull secret configured in RegistryPullSecrets, or nil if none is configured.
+func lookupRegistryCredential(ctx context.Context, kubeClient k8sclient.Interface, host string, args ImageCompatibilityPluginArgs) (*registryCredential, error) {
+	secretRef, ok := args.RegistryPullSecrets[host]
+	if !ok {
+		return nil, nil
+	}
+	nn, err := parseNamespacedName(secretRef)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := getPullSecret(ctx, kubeClient, nn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pull secret %s for registry %s: %w", nn, host, err)
+	}
+
+	cred, err := credentialFromSecret(secret, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pull secret %s for registry %s: %w", nn, host, err)
+	}
+	return cred, nil
+}
+
+// credentialFromSecret extracts the credential for host from a
+// kubernetes.io/dockerconfigjson or kubernetes.io/dockercfg secret.
+func credentialFromSecret(secret *v1.Secret, host string) (*registryCredential, error) {
+	var auths map[string]dockerConfigEntry
+	switch secret.Type {
+	case v1.SecretTypeDockerConfigJson:
+		var cfg dockerConfigJSON
+		if err := json.Unmarshal(secret.Data[v1.DockerConfigJsonKey], &cfg); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", v1.DockerConfigJsonKey, err)
+		}
+		auths = cfg.Auths
+	case v1.SecretTypeDockercfg:
+		if err := json.Unmarshal(secret.Data[v1.DockerConfigKey], &auths); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", v1.DockerConfigKey, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported secret type %s", secret.Type)
+	}
+
+	for key, entry := range auths {
+		if normalizeRegistryHost(key) != normalizeRegistryHost(host) {
+			continue
+		}
+		cred := &registryCredential{
+			Username:      entry.Username,
+			Password:      entry.Password,
+			IdentityToken: entry.IdentityToken,
+		}
+		if entry.Auth != "" && cred.Username == "" {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("invalid auth for registry %s: %w", key, err)
+			}
+			cred.Username, cred.Password, _ = strings.Cut(string(decoded), ":")
+		}
+		return cred, nil
+	}
+	return nil, fmt.Errorf("no credential for registry %s", host)
+}
+
+// normalizeRegistryHost strips the scheme and path of a docker config key and
+// maps the Docker Hub aliases to docker.io.
+func normalizeRegistryHost(host string) string {
+	host = strings.TrimPrefix(host, "https://")
+	host = strings.TrimPrefix(host, "http://")
+	host, _, _ = strings.Cut(host, "/")
+	switch host {
+	case "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	}
+	return host
+}
@@ -0,0 +1,118 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLookupRegistryCredential(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "shared-pull", Namespace: "registry-creds"},
+			Type:       v1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{
+				// "robot:s3cret" base64 encoded
+				v1.DockerConfigJsonKey: []byte(`{"auths":{"https://registry.example.com/v2/":{"auth":"cm9ib3Q6czNjcmV0"},"https://index.docker.io/v1/":{"username":"hub","password":"token"}}}`),
+			},
+		},
+	)
+	args := ImageCompatibilityPluginArgs{
+		RegistryPullSecrets: map[string]string{
+			"registry.example.com": "registry-creds/shared-pull",
+			"docker.io":            "registry-creds/shared-pull",
+			"missing.example.com":  "registry-creds/does-not-exist",
+		},
+	}
+
+	cred, err := lookupRegistryCredential(context.Background(), kubeClient, "registry.example.com", args)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cred == nil || cred.Username != "robot" || cred.Password != "s3cret" {
+		t.Errorf("unexpected credential for registry.example.com: %+v", cred)
+	}
+
+	cred, err = lookupRegistryCredential(context.Background(), kubeClient, "docker.io", args)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cred == nil || cred.Username != "hub" || cred.Password != "token" {
+		t.Errorf("unexpected credential for docker.io: %+v", cred)
+	}
+
+	cred, err = lookupRegistryCredential(context.Background(), kubeClient, "quay.io", args)
+	if err != nil || cred != nil {
+		t.Errorf("expected no credential for unconfigured registry, got %+v, %v", cred, err)
+	}
+
+	if _, err := lookupRegistryCredential(context.Background(), kubeClient, "missing.example.com", args); err == nil {
+		t.Error("expected error for missing secret")
+	}
+
+	// The pull secret is read once and reused across lookups
+	gets := 0
+	for _, action := range kubeClient.Actions() {
+		if action.Matches("get", "secrets") {
+			gets++
+		}
+	}
+	if gets != 2 {
+		t.Errorf("expected the shared pull secret and the missing one to be read once each, got %d gets", gets)
+	}
+}
+
+func TestIdentityTokenCredential(t *testing.T) {
+	var grants []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			_ = r.ParseForm()
+			grants = append(grants, r.PostForm.Get("grant_type")+":"+r.PostForm.Get("refresh_token"))
+			_, _ = w.Write([]byte(`{"access_token":"access"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer access" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "token-pull", Namespace: "registry-creds"},
+		Type:       v1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			v1.DockerConfigJsonKey: []byte(`{"auths":{"` + host + `":{"username":"<token>","identitytoken":"identity"}}}`),
+		},
+	}
+	cred, err := credentialFromSecret(secret, host)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cred.IdentityToken != "identity" || cred.Password != "" {
+		t.Errorf("unexpected credential: %+v", cred)
+	}
+
+	args := ImageCompatibilityPluginArgs{PlainHttp: true, RegistryPullSecrets: map[string]string{host: "registry-creds/token-pull"}}
+	client, err := newImageArtifactClient(context.Background(), fake.NewSimpleClientset(secret), host+"/app:v1", args)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The identity token is exchanged for an access token, not sent as one
+	if _, err := client.FetchCompatibilitySpec(context.Background()); err == nil {
+		t.Error("expected the missing image to fail the fetch")
+	}
+	if len(grants) == 0 || grants[0] != "refresh_token:identity" {
+		t.Errorf("expected a refresh_token grant with the identity token, got %v", grants)
+	}
+}
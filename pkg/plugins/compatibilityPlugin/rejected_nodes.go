@@ -0,0 +1,69 @@
+package compatibilityPlugin
+
+import (
+	"fmt"
+	"maps"
+
+	fwk "k8s.io/kube-scheduler/framework"
+)
+
+// RejectedNodesStateKey is the CycleState key of the RejectedNodes marker.
+//
+// The plugin is expensive compared to most Filter plugins, so it should be
+// configured to run last. Plugins running before it, typically in PreFilter,
+// can additionally write a *RejectedNodes under this key for the nodes they
+// already know the pod cannot run on. The plugin then leaves those nodes out
+// of the compatibility evaluation in PreFilter and rejects them in Filter
+// with the recorded reason, without consulting the compatibility state.
+// Writers must add to an existing marker rather than replace it.
+const RejectedNodesStateKey fwk.StateKey = PluginName + "/rejected-nodes"
+
+// RejectedNodes is the CycleState marker of nodes that are already known to
+// be unschedulable for the pod, mapped to the reason.
+type RejectedNodes struct {
+	Nodes map[string]string
+}
+
+// Clone implements the scheduler framework StateData interface.
+func (r *RejectedNodes) Clone() fwk.StateData {
+	return &RejectedNodes{Nodes: maps.Clone(r.Nodes)}
+}
+
+// readRejectedNodes returns the nodes of the RejectedNodes marker, nil when
+// no plugin wrote one.
+func readRejectedNodes(cycleState fwk.CycleState) map[string]string {
+	data, err := cycleState.Read(RejectedNodesStateKey)
+	if err != nil {
+		return nil
+	}
+	rejected, ok := data.(*RejectedNodes)
+	if !ok {
+		return nil
+	}
+	return rejected.Nodes
+}
+
+// withoutRejectedNodes returns the nodes missing from rejected.
+func withoutRejectedNodes(nodes []fwk.NodeInfo, rejected map[string]string) []fwk.NodeInfo {
+	if len(rejected) == 0 {
+		return nodes
+	}
+	kept := make([]fwk.NodeInfo, 0, len(nodes))
+	for _, nodeInfo := range nodes {
+		if node := nodeInfo.Node(); node != nil {
+			if _, ok := rejected[node.Name]; ok {
+				continue
+			}
+		}
+		kept = append(kept, nodeInfo)
+	}
+	return kept
+}
+
+// rejectedNodeReason returns the Filter reason of a node rejected by the marker.
+func rejectedNodeReason(nodeName, reason string) string {
+	if reason == "" {
+		return fmt.Sprintf("node %s was already rejected", nodeName)
+	}
+	return fmt.Sprintf("node %s was already rejected: %s", nodeName, reason)
+}
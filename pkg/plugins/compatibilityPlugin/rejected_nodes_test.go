@@ -0,0 +1,43 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fwk "k8s.io/kube-scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func TestRejectedNodes(t *testing.T) {
+	var nodes []fwk.NodeInfo
+	for _, name := range []string{"node1", "node2"} {
+		ni := framework.NewNodeInfo()
+		ni.SetNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		nodes = append(nodes, ni)
+	}
+
+	cycleState := framework.NewCycleState()
+	if readRejectedNodes(cycleState) != nil {
+		t.Errorf("expected no rejected nodes without marker")
+	}
+	cycleState.Write(RejectedNodesStateKey, &RejectedNodes{Nodes: map[string]string{"node1": "insufficient gpu"}})
+	// Both nodes are compatible, the marker takes precedence
+	cycleState.Write(PluginName, &CompatibilityState{CompatibleNodes: map[string]struct{}{"node1": {}, "node2": {}}})
+
+	kept := withoutRejectedNodes(nodes, readRejectedNodes(cycleState))
+	if len(kept) != 1 || kept[0].Node().Name != "node2" {
+		t.Errorf("expected only node2 to be kept, got %d nodes", len(kept))
+	}
+
+	plugin := &ImageCompatibilityPlugin{}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p"}}
+	status := plugin.Filter(context.Background(), cycleState, pod, nodes[0])
+	if status.Code() != fwk.Unschedulable || status.Message() != "node node1 was already rejected: insufficient gpu" {
+		t.Errorf("unexpected status for node1: %v", status)
+	}
+	if status := plugin.Filter(context.Background(), cycleState, pod, nodes[1]); !status.IsSuccess() {
+		t.Errorf("expected Success for node2, got %v", status)
+	}
+}
@@ -0,0 +1,69 @@
+package compatibilityPlugin
+
+import (
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// requiredNFGIndex records the required NodeFeatureGroups of the pods that
+// PreFilter left with incompatible nodes, so that only membership changes of
+// those groups requeue them.
+type requiredNFGIndex struct {
+	mu   sync.Mutex
+	pods map[types.UID]requiredNFGs
+}
+
+// requiredNFGs holds the required NodeFeatureGroups of a pod.
+type requiredNFGs struct {
+	namespace string
+	name      string
+	nfgs      map[string]struct{}
+}
+
+func newRequiredNFGIndex() *requiredNFGIndex {
+	return &requiredNFGIndex{pods: make(map[types.UID]requiredNFGs)}
+}
+
+// record sets the required NodeFeatureGroups of the pod.
+func (r *requiredNFGIndex) record(pod *v1.Pod, nfgNames []string) {
+	entry := requiredNFGs{namespace: pod.Namespace, name: pod.Name, nfgs: make(map[string]struct{}, len(nfgNames))}
+	for _, name := range nfgNames {
+		entry.nfgs[name] = struct{}{}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pods[pod.UID] = entry
+}
+
+// forget removes the pod from the index.
+func (r *requiredNFGIndex) forget(uid types.UID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pods, uid)
+}
+
+// requires reports whether the NodeFeatureGroup is required by the pod. Pods
+// that are not recorded may require any group.
+func (r *requiredNFGIndex) requires(uid types.UID, nfgName string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry, found := r.pods[uid]
+	if !found {
+		return true
+	}
+	_, required := entry.nfgs[nfgName]
+	return required
+}
+
+// prune removes the pods that are no longer pending according to pending.
+func (r *requiredNFGIndex) prune(pending func(namespace, name string, uid types.UID) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for uid, entry := range r.pods {
+		if !pending(entry.namespace, entry.name, uid) {
+			delete(r.pods, uid)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package compatibilityPlugin
+
+import (
+	"encoding/json"
+	"log"
+	"slices"
+
+	v1 "k8s.io/api/core/v1"
+	fwk "k8s.io/kube-scheduler/framework"
+)
+
+const (
+	// ResultLogFormatText logs compatibility results as human readable text.
+	ResultLogFormatText = "text"
+	// ResultLogFormatJSON logs compatibility results as a single JSON line.
+	ResultLogFormatJSON = "json"
+
+	// CompatibilitySourceNodeFeatureGroups marks results computed by nfd-master
+	// from the NodeFeatureGroups created for the images.
+	CompatibilitySourceNodeFeatureGroups = "nodeFeatureGroups"
+	// CompatibilitySourceInstanceType marks results computed in-process from the
+	// instance type feature sets.
+	CompatibilitySourceInstanceType = "instanceType"
+	// CompatibilitySourceCustomFeatures marks results computed in-process from
+	// the NFD features merged with the custom features ConfigMap.
+	CompatibilitySourceCustomFeatures = "customFeatures"
+)
+
+// CompatibilityResult is the outcome of evaluating the images of a pod
+// against the nodes of the cluster.
+type CompatibilityResult struct {
+	Pod               string       `json:"pod"`
+	Owner             *ResultOwner `json:"owner,omitempty"`
+	Images            []string     `json:"images"`
+	Source            string       `json:"source"`
+	NodeFeatureGroups []string     `json:"nodeFeatureGroups,omitempty"`
+	CompatibleNodes   []string     `json:"compatibleNodes"`
+	IncompatibleNodes []string     `json:"incompatibleNodes"`
+}
+
+// newCompatibilityResult builds the result of a scheduling cycle, splitting
+// the evaluated nodes by the set of compatible nodes.
+func newCompatibilityResult(pod *v1.Pod, images []string, source string, nfgNames []string, compatibleNodes map[string]struct{}, nodes []fwk.NodeInfo) *CompatibilityResult {
+	result := &CompatibilityResult{
+		Pod:               pod.Namespace + "/" + pod.Name,
+		Images:            images,
+		Source:            source,
+		NodeFeatureGroups: nfgNames,
+		CompatibleNodes:   []string{},
+		IncompatibleNodes: []string{},
+	}
+	for _, nodeInfo := range nodes {
+		node := nodeInfo.Node()
+		if node == nil {
+			continue
+		}
+		if _, ok := compatibleNodes[node.Name]; ok {
+			result.CompatibleNodes = append(result.CompatibleNodes, node.Name)
+		} else {
+			result.IncompatibleNodes = append(result.IncompatibleNodes, node.Name)
+		}
+	}
+	slices.Sort(result.CompatibleNodes)
+	slices.Sort(result.IncompatibleNodes)
+	return result
+}
+
+// reportResult logs the compatibility result of the pod and queues it for the
+// result webhook, if configured.
+func (f *ImageCompatibilityPlugin) reportResult(pod *v1.Pod, result *CompatibilityResult) {
+	result.Owner = f.resultOwner(pod)
+	f.logResult(result)
+	f.resultWebhook.enqueue(result)
+}
+
+// logResult logs the compatibility result in the configured ResultLogFormat.
+func (f *ImageCompatibilityPlugin) logResult(result *CompatibilityResult) {
+	if f.args.ResultLogFormat == ResultLogFormatJSON {
+		data, err := json.Marshal(result)
+		if err != nil {
+			log.Printf("Failed to marshal compatibility result of pod %s: %v", result.Pod, err)
+			return
+		}
+		log.Print(string(data))
+		return
+	}
+	log.Printf("Compatibility result for pod %s (%s): images %v, %d compatible nodes %v, %d incompatible nodes",
+		result.Pod, result.Source, result.Images, len(result.CompatibleNodes), result.CompatibleNodes, len(result.IncompatibleNodes))
+}
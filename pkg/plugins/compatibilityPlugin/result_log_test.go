@@ -0,0 +1,46 @@
+package compatibilityPlugin
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fwk "k8s.io/kube-scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func TestNewCompatibilityResult(t *testing.T) {
+	var nodes []fwk.NodeInfo
+	for _, name := range []string{"node-b", "node-a", "node-c"} {
+		nodeInfo := framework.NewNodeInfo()
+		nodeInfo.SetNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		nodes = append(nodes, nodeInfo)
+	}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+	compatible := map[string]struct{}{"node-b": {}, "node-a": {}}
+
+	result := newCompatibilityResult(pod, []string{"docker.io/library/app:v1"}, CompatibilitySourceNodeFeatureGroups,
+		[]string{"image-compat-app-x"}, compatible, nodes)
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid JSON %s: %v", data, err)
+	}
+	want := map[string]interface{}{
+		"pod":               "default/app",
+		"images":            []interface{}{"docker.io/library/app:v1"},
+		"source":            CompatibilitySourceNodeFeatureGroups,
+		"nodeFeatureGroups": []interface{}{"image-compat-app-x"},
+		"compatibleNodes":   []interface{}{"node-a", "node-b"},
+		"incompatibleNodes": []interface{}{"node-c"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("result = %s, want %v", data, want)
+	}
+}
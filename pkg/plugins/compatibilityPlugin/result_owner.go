@@ -0,0 +1,87 @@
+package compatibilityPlugin
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+)
+
+// maxOwnerDepth bounds the walk up the controller owner references of a pod.
+const maxOwnerDepth = 5
+
+// ResultOwner is the top-level controller of a pod, e.g. its Deployment,
+// reported with compatibility results for aggregation.
+type ResultOwner struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// ownerReferencesFunc returns the owner references of the object of the
+// kind in namespace, and false when the object is unknown or its owners are
+// not followed.
+type ownerReferencesFunc func(namespace, kind, name string) ([]metav1.OwnerReference, bool)
+
+// resolveResultOwner follows the controller owner references of the pod up to
+// the top-level controller, nil for pods without a controller.
+func resolveResultOwner(pod *v1.Pod, ownerReferences ownerReferencesFunc) *ResultOwner {
+	ref := metav1.GetControllerOfNoCopy(pod)
+	if ref == nil {
+		return nil
+	}
+	owner := &ResultOwner{Kind: ref.Kind, Name: ref.Name}
+	for range maxOwnerDepth {
+		refs, ok := ownerReferences(pod.Namespace, owner.Kind, owner.Name)
+		if !ok {
+			break
+		}
+		controller := controllerOf(refs)
+		if controller == nil {
+			break
+		}
+		owner = &ResultOwner{Kind: controller.Kind, Name: controller.Name}
+	}
+	return owner
+}
+
+// controllerOf returns the controller among the owner references, if any.
+func controllerOf(refs []metav1.OwnerReference) *metav1.OwnerReference {
+	for i := range refs {
+		if refs[i].Controller != nil && *refs[i].Controller {
+			return &refs[i]
+		}
+	}
+	return nil
+}
+
+// newInformerOwnerReferences returns the owner references of ReplicaSets and
+// Jobs from the informer caches, so that Deployments and CronJobs are
+// resolved without API calls while scheduling. Other kinds are top-level.
+func newInformerOwnerReferences(factory informers.SharedInformerFactory) ownerReferencesFunc {
+	replicaSets := factory.Apps().V1().ReplicaSets().Lister()
+	jobs := factory.Batch().V1().Jobs().Lister()
+	return func(namespace, kind, name string) ([]metav1.OwnerReference, bool) {
+		switch kind {
+		case "ReplicaSet":
+			rs, err := replicaSets.ReplicaSets(namespace).Get(name)
+			if err != nil {
+				return nil, false
+			}
+			return rs.OwnerReferences, true
+		case "Job":
+			job, err := jobs.Jobs(namespace).Get(name)
+			if err != nil {
+				return nil, false
+			}
+			return job.OwnerReferences, true
+		}
+		return nil, false
+	}
+}
+
+// resultOwner returns the top-level owner of the pod when ResultOwners is set.
+func (f *ImageCompatibilityPlugin) resultOwner(pod *v1.Pod) *ResultOwner {
+	if f.ownerReferences == nil {
+		return nil
+	}
+	return resolveResultOwner(pod, f.ownerReferences)
+}
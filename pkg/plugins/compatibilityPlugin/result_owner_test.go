@@ -0,0 +1,48 @@
+package compatibilityPlugin
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestResolveResultOwner(t *testing.T) {
+	controller := true
+	controlledBy := func(kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}}
+	}
+	objects := map[string][]metav1.OwnerReference{
+		"ReplicaSet/web-7d9f":       controlledBy("Deployment", "web"),
+		"ReplicaSet/orphan-5c4b":    nil,
+		"Job/backup-28471230":       controlledBy("CronJob", "backup"),
+		"Job/manual-backup":         {{Kind: "CronJob", Name: "backup"}}, // Not a controller
+		"ReplicaSet/missing-parent": controlledBy("Deployment", "gone"),
+	}
+	ownerReferences := func(_, kind, name string) ([]metav1.OwnerReference, bool) {
+		refs, ok := objects[kind+"/"+name]
+		return refs, ok
+	}
+
+	tests := []struct {
+		name  string
+		owner []metav1.OwnerReference
+		want  *ResultOwner
+	}{
+		{name: "deployment", owner: controlledBy("ReplicaSet", "web-7d9f"), want: &ResultOwner{Kind: "Deployment", Name: "web"}},
+		{name: "orphaned replicaset", owner: controlledBy("ReplicaSet", "orphan-5c4b"), want: &ResultOwner{Kind: "ReplicaSet", Name: "orphan-5c4b"}},
+		{name: "cronjob", owner: controlledBy("Job", "backup-28471230"), want: &ResultOwner{Kind: "CronJob", Name: "backup"}},
+		{name: "job without controller", owner: controlledBy("Job", "manual-backup"), want: &ResultOwner{Kind: "Job", Name: "manual-backup"}},
+		{name: "statefulset", owner: controlledBy("StatefulSet", "db"), want: &ResultOwner{Kind: "StatefulSet", Name: "db"}},
+		{name: "no controller", owner: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-7d9f"}}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p", Namespace: "default", OwnerReferences: tt.owner}}
+			got := resolveResultOwner(pod, ownerReferences)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("expected owner %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package compatibilityPlugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sclient "k8s.io/client-go/kubernetes"
+)
+
+const (
+	// DefaultResultWebhookTimeout bounds each POST to the result webhook.
+	DefaultResultWebhookTimeout = 5 * time.Second
+	// ResultWebhookAuthSecretKey is the key of the ResultWebhookAuthSecret
+	// holding the Authorization header value.
+	ResultWebhookAuthSecretKey = "authorization"
+
+	resultWebhookAttempts  = 3
+	resultWebhookBackoff   = 500 * time.Millisecond
+	resultWebhookQueueSize = 1000
+)
+
+// resultWebhookEvent is the payload posted to the result webhook.
+type resultWebhookEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	*CompatibilityResult
+}
+
+// resultWebhook posts compatibility results to an external endpoint from a
+// background worker, so that the webhook latency never slows scheduling.
+type resultWebhook struct {
+	url     string
+	client  *http.Client
+	backoff time.Duration
+	queue   chan resultWebhookEvent
+	// authorization returns the Authorization header value, empty for none
+	authorization func(ctx context.Context) (string, error)
+}
+
+// newResultWebhook creates the webhook configured by args, nil when
+// ResultWebhookURL is not set.
+func newResultWebhook(kubeClient k8sclient.Interface, args ImageCompatibilityPluginArgs) (*resultWebhook, error) {
+	if args.ResultWebhookURL == "" {
+		return nil, nil
+	}
+	timeout := args.ResultWebhookTimeout.Duration
+	if timeout == 0 {
+		timeout = DefaultResultWebhookTimeout
+	}
+
+	w := &resultWebhook{
+		url:           args.ResultWebhookURL,
+		client:        &http.Client{Timeout: timeout},
+		backoff:       resultWebhookBackoff,
+		queue:         make(chan resultWebhookEvent, resultWebhookQueueSize),
+		authorization: func(context.Context) (string, error) { return "", nil },
+	}
+	if args.ResultWebhookAuthSecret != "" {
+		nn, err := parseNamespacedName(args.ResultWebhookAuthSecret)
+		if err != nil {
+			return nil, err
+		}
+		// Read the secret on every post so that rotated tokens are picked up
+		w.authorization = func(ctx context.Context) (string, error) {
+			secret, err := kubeClient.CoreV1().Secrets(nn.Namespace).Get(ctx, nn.Name, metav1.GetOptions{})
+			if err != nil {
+				return "", fmt.Errorf("failed to get webhook auth secret %s: %w", nn, err)
+			}
+			value, ok := secret.Data[ResultWebhookAuthSecretKey]
+			if !ok {
+				return "", fmt.Errorf("webhook auth secret %s has no %s key", nn, ResultWebhookAuthSecretKey)
+			}
+			return string(value), nil
+		}
+	}
+	return w, nil
+}
+
+// enqueue queues the result for posting, dropping it when the queue is full.
+func (w *resultWebhook) enqueue(result *CompatibilityResult) {
+	if w == nil {
+		return
+	}
+	select {
+	case w.queue <- resultWebhookEvent{Timestamp: time.Now().UTC(), CompatibilityResult: result}:
+	default:
+		log.Printf("Result webhook queue is full, dropping the result of pod %s", result.Pod)
+	}
+}
+
+// run posts the queued results until ctx is done.
+func (w *resultWebhook) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-w.queue:
+			if err := w.post(ctx, event); err != nil {
+				log.Printf("Failed to post the result of pod %s to the result webhook: %v", event.Pod, err)
+			}
+		}
+	}
+}
+
+// post posts the event, retrying network errors and 5xx or 429 responses.
+func (w *resultWebhook) post(ctx context.Context, event resultWebhookEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	authorization, err := w.authorization(ctx)
+	if err != nil {
+		return err
+	}
+
+	backoff := w.backoff
+	for attempt := 1; ; attempt++ {
+		retryable, err := w.postOnce(ctx, body, authorization)
+		if err == nil || !retryable || attempt == resultWebhookAttempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// postOnce posts the body once, reporting whether a failure may be retried.
+func (w *resultWebhook) postOnce(ctx context.Context, body []byte, authorization string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retryable, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return false, nil
+}
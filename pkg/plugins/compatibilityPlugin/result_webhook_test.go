@@ -0,0 +1,75 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestResultWebhook(t *testing.T) {
+	var calls atomic.Int32
+	received := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first post fails and is retried
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("expected Authorization header, got %q", got)
+		}
+		var event map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		received <- event
+	}))
+	defer server.Close()
+
+	kubeClient := fake.NewSimpleClientset(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "custom-scheduler", Name: "webhook"},
+		Data:       map[string][]byte{ResultWebhookAuthSecretKey: []byte("Bearer token")},
+	})
+	w, err := newResultWebhook(kubeClient, ImageCompatibilityPluginArgs{
+		ResultWebhookURL:        server.URL,
+		ResultWebhookAuthSecret: "custom-scheduler/webhook",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w.backoff = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.run(ctx)
+	w.enqueue(&CompatibilityResult{Pod: "default/app", Images: []string{"app:v1"}, CompatibleNodes: []string{"node1"}})
+
+	select {
+	case event := <-received:
+		if event["pod"] != "default/app" || event["timestamp"] == nil {
+			t.Errorf("unexpected payload %v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the webhook post")
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("expected 2 posts, got %d", got)
+	}
+}
+
+func TestResultWebhookDisabled(t *testing.T) {
+	w, err := newResultWebhook(nil, ImageCompatibilityPluginArgs{})
+	if err != nil || w != nil {
+		t.Fatalf("expected no webhook, got %v, %v", w, err)
+	}
+	// Enqueueing on a disabled webhook is a no-op
+	w.enqueue(&CompatibilityResult{Pod: "default/app"})
+}
@@ -0,0 +1,86 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
+)
+
+// RuntimeClassTagPrefix prefixes the tag of the compatibility sets that only
+// apply to pods of a runtime class, e.g. "runtimeClass:kata".
+const RuntimeClassTagPrefix = "runtimeClass:"
+
+// podRuntimeClass returns the runtime class of the pod, empty for the default.
+func podRuntimeClass(pod *v1.Pod) string {
+	if pod.Spec.RuntimeClassName == nil {
+		return ""
+	}
+	return *pod.Spec.RuntimeClassName
+}
+
+// selectRuntimeClassSets returns the indexes of the compatibility sets, given
+// by their tags, that apply to the runtime class: the sets tagged for it if
+// the image has any, otherwise the sets not tagged for a runtime class.
+func selectRuntimeClassSets(tags []string, runtimeClass string) []int {
+	var specific, defaults []int
+	for i, tag := range tags {
+		class, ok := strings.CutPrefix(tag, RuntimeClassTagPrefix)
+		switch {
+		case !ok:
+			defaults = append(defaults, i)
+		case runtimeClass != "" && class == runtimeClass:
+			specific = append(specific, i)
+		}
+	}
+	if len(specific) > 0 {
+		return specific
+	}
+	return defaults
+}
+
+// selectRuntimeClassGroups returns the groups of an image that apply to the
+// runtime class.
+func selectRuntimeClassGroups(groups []nfdv1alpha1.NodeFeatureGroup, runtimeClass string) []nfdv1alpha1.NodeFeatureGroup {
+	tags := make([]string, len(groups))
+	for i := range groups {
+		tags[i] = groups[i].Annotations[NFGTagAnnotation]
+	}
+	indexes := selectRuntimeClassSets(tags, runtimeClass)
+	if len(indexes) == len(groups) {
+		return groups
+	}
+	selected := make([]nfdv1alpha1.NodeFeatureGroup, 0, len(indexes))
+	for _, i := range indexes {
+		selected = append(selected, groups[i])
+	}
+	return selected
+}
+
+// selectRuntimeClassNFGs returns the NodeFeatureGroups of each image that
+// apply to the runtime class.
+func (f *ImageCompatibilityPlugin) selectRuntimeClassNFGs(ctx context.Context, namespace string, imageNFGs map[string][]string, runtimeClass string) (map[string][]string, error) {
+	nfdCli, err := f.getNfdClient()
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string][]string, len(imageNFGs))
+	for image, nfgNames := range imageNFGs {
+		tags := make([]string, len(nfgNames))
+		for i, nfgName := range nfgNames {
+			nfg, err := nfdCli.NfdV1alpha1().NodeFeatureGroups(namespace).Get(ctx, nfgName, metav1.GetOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get NodeFeatureGroup %s: %w", nfgName, err)
+			}
+			tags[i] = nfg.Annotations[NFGTagAnnotation]
+		}
+		for _, i := range selectRuntimeClassSets(tags, runtimeClass) {
+			selected[image] = append(selected[image], nfgNames[i])
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,26 @@
+package compatibilityPlugin
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectRuntimeClassSets(t *testing.T) {
+	tags := []string{"", "runtimeClass:kata", "gpu", "runtimeClass:gvisor"}
+	tests := []struct {
+		name         string
+		runtimeClass string
+		want         []int
+	}{
+		{name: "no runtime class", want: []int{0, 2}},
+		{name: "tagged runtime class", runtimeClass: "kata", want: []int{1}},
+		{name: "untagged runtime class", runtimeClass: "runc", want: []int{0, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selectRuntimeClassSets(tags, tt.runtimeClass); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectRuntimeClassSets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fwk "k8s.io/kube-scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
+)
+
+const (
+	// NFGWeightAnnotation holds the weight of the compatibility set a
+	// NodeFeatureGroup was created from.
+	NFGWeightAnnotation = "image-compat.scheduler/weight"
+	// NFGTagAnnotation holds the tag of the compatibility set a
+	// NodeFeatureGroup was created from.
+	NFGTagAnnotation = "image-compat.scheduler/tag"
+)
+
+// preferredSet is a weighted compatibility set and the nodes satisfying it.
+// Weighted sets are preferences: they do not filter nodes, nodes satisfying
+// them are scored higher. Unweighted sets are required.
+type preferredSet struct {
+	weight int
+	nodes  map[string]struct{}
+}
+
+// compatibilityWeight returns the weight of the compatibility set of the
+// NodeFeatureGroup, zero for required sets.
+func compatibilityWeight(nfg *nfdv1alpha1.NodeFeatureGroup) int {
+	raw, ok := nfg.Annotations[NFGWeightAnnotation]
+	if !ok {
+		return 0
+	}
+	weight, err := strconv.Atoi(raw)
+	if err != nil || weight < 0 {
+		log.Printf("Ignoring invalid weight %q of NodeFeatureGroup %s, treating it as required", raw, nfg.Name)
+		return 0
+	}
+	return weight
+}
+
+// splitGroupsByWeight splits compatibility sets that are evaluated in-process,
+// without creating NodeFeatureGroups, like splitByWeight: into the required
+// ones and the weighted, preferred ones.
+func splitGroupsByWeight(groups []nfdv1alpha1.NodeFeatureGroup) (required, preferred []nfdv1alpha1.NodeFeatureGroup) {
+	for _, group := range groups {
+		if compatibilityWeight(&group) > 0 {
+			preferred = append(preferred, group)
+		} else {
+			required = append(required, group)
+		}
+	}
+	return required, preferred
+}
+
+// splitByWeight splits NodeFeatureGroups into the names of the required ones
+// and the weights of the preferred ones.
+func (f *ImageCompatibilityPlugin) splitByWeight(ctx context.Context, namespace string, nfgNames []string) (required []string, preferred map[string]int, err error) {
+	nfdCli, err := f.getNfdClient()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	preferred = make(map[string]int)
+	for _, nfgName := range nfgNames {
+		nfg, err := nfdCli.NfdV1alpha1().NodeFeatureGroups(namespace).Get(ctx, nfgName, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get NodeFeatureGroup %s: %w", nfgName, err)
+		}
+		if weight := compatibilityWeight(nfg); weight > 0 {
+			preferred[nfgName] = weight
+		} else {
+			required = append(required, nfgName)
+		}
+	}
+	return required, preferred, nil
+}
+
+// nodeScores scores nodes by the weights of the preferred sets they satisfy,
+// normalized to [0, MaxNodeScore] by the total weight. Scores are absolute,
+// so nodes satisfying the same sets tie and the scheduler picks among them.
+func nodeScores(sets []preferredSet, nodeNames []string) map[string]int64 {
+	total := 0
+	for _, set := range sets {
+		total += set.weight
+	}
+	if total == 0 {
+		return nil
+	}
+
+	scores := make(map[string]int64, len(nodeNames))
+	for _, nodeName := range nodeNames {
+		satisfied := 0
+		for _, set := range sets {
+			if _, ok := set.nodes[nodeName]; ok {
+				satisfied += set.weight
+			}
+		}
+		scores[nodeName] = int64(satisfied) * framework.MaxNodeScore / int64(total)
+	}
+	return scores
+}
+
+// Score ranks compatible nodes by the weights of the preferred compatibility
+// sets they satisfy, as computed in PreFilter.
+func (f *ImageCompatibilityPlugin) Score(ctx context.Context, cycleState fwk.CycleState, pod *v1.Pod, nodeInfo fwk.NodeInfo) (int64, *fwk.Status) {
+	state, err := getCompatibilityState(cycleState)
+	if err != nil {
+		// PreFilter was skipped, the pod images have no requirements
+		return 0, nil
+	}
+	return state.NodeScores[nodeInfo.Node().Name], nil
+}
+
+// ScoreExtensions returns nil, scores are already normalized.
+func (f *ImageCompatibilityPlugin) ScoreExtensions() framework.ScoreExtensions {
+	return nil
+}
@@ -0,0 +1,103 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	compatv1alpha1 "sigs.k8s.io/node-feature-discovery/api/image-compatibility/v1alpha1"
+)
+
+func TestNodeScores(t *testing.T) {
+	sets := []preferredSet{
+		{weight: 3, nodes: map[string]struct{}{"optimal": {}}},
+		{weight: 1, nodes: map[string]struct{}{"optimal": {}, "acceptable": {}}},
+	}
+
+	got := nodeScores(sets, []string{"optimal", "acceptable", "other"})
+	want := map[string]int64{"optimal": 100, "acceptable": 25, "other": 0}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("nodeScores() = %v, want %v", got, want)
+	}
+
+	if got := nodeScores(nil, []string{"optimal"}); got != nil {
+		t.Errorf("expected no scores without preferred sets, got %v", got)
+	}
+}
+
+func TestScore(t *testing.T) {
+	plugin := &ImageCompatibilityPlugin{}
+	nodeInfo := framework.NewNodeInfo()
+	nodeInfo.SetNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "optimal"}})
+
+	cycleState := framework.NewCycleState()
+	if score, status := plugin.Score(context.Background(), cycleState, &v1.Pod{}, nodeInfo); score != 0 || !status.IsSuccess() {
+		t.Errorf("expected zero score without state, got %d, %v", score, status)
+	}
+
+	cycleState.Write(PluginName, &CompatibilityState{NodeScores: map[string]int64{"optimal": 75}})
+	if score, status := plugin.Score(context.Background(), cycleState, &v1.Pod{}, nodeInfo); score != 75 || !status.IsSuccess() {
+		t.Errorf("expected score 75, got %d, %v", score, status)
+	}
+}
+
+func TestTransferFromArtifact_PreservesWeight(t *testing.T) {
+	spec := &compatv1alpha1.Spec{
+		Compatibilties: []compatv1alpha1.Compatibility{
+			{Weight: 10, Tag: "optimal"},
+			{},
+		},
+	}
+	groups, err := NewFeatureGroupManagement(&MockArtifactClient{spec: spec}).TransferFromArtifact(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(groups))
+	}
+	if got := compatibilityWeight(&groups[0]); got != 10 {
+		t.Errorf("expected weight 10, got %d", got)
+	}
+	if got := groups[0].Annotations[NFGTagAnnotation]; got != "optimal" {
+		t.Errorf("expected tag optimal, got %q", got)
+	}
+	if got := compatibilityWeight(&groups[1]); got != 0 {
+		t.Errorf("expected unweighted set to be required, got weight %d", got)
+	}
+}
+
+func TestMeetsMatchRatio(t *testing.T) {
+	tests := []struct {
+		matched, total int
+		ratio          float64
+		want           bool
+	}{
+		{matched: 5, total: 5, ratio: 1, want: true},
+		{matched: 4, total: 5, ratio: 1, want: false},
+		{matched: 4, total: 5, ratio: 0.8, want: true},
+		{matched: 3, total: 5, ratio: 0.8, want: false},
+		{matched: 0, total: 0, ratio: 1, want: true},
+		{matched: 0, total: 3, ratio: 0, want: true},
+	}
+
+	for _, tt := range tests {
+		if got := meetsMatchRatio(tt.matched, tt.total, tt.ratio); got != tt.want {
+			t.Errorf("meetsMatchRatio(%d, %d, %v) = %v, want %v", tt.matched, tt.total, tt.ratio, got, tt.want)
+		}
+	}
+}
+
+func TestValidateArgs_MinMatchRatio(t *testing.T) {
+	for _, ratio := range []float64{-0.1, 1.5} {
+		if err := validateArgs(&ImageCompatibilityPluginArgs{MinMatchRatio: &ratio}); err == nil {
+			t.Errorf("expected error for minMatchRatio %v", ratio)
+		}
+	}
+	ratio := 0.8
+	if err := validateArgs(&ImageCompatibilityPluginArgs{MinMatchRatio: &ratio}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// startHTTPServer serves the plugin health and debug endpoints until ctx is done.
+func (f *ImageCompatibilityPlugin) startHTTPServer(ctx context.Context, addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
+		if err := f.health.check(); err != nil {
+			http.Error(w, fmt.Sprintf("%s not ready: %v", PluginName, err), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	})
+	mux.HandleFunc("/debug/simulate", f.serveSimulate)
+	mux.HandleFunc("/debug/cache", f.serveCache)
+	mux.HandleFunc("/debug/artifacts", f.serveArtifacts)
+	mux.HandleFunc("/debug/decisions", f.serveDecisions)
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Failed to shut down HTTP server: %v", err)
+		}
+	}()
+
+	log.Printf("Serving %s HTTP endpoints on %s", PluginName, addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("HTTP server on %s stopped: %v", addr, err)
+	}
+}
+
+// simulateResponse is the body returned by /debug/simulate.
+type simulateResponse struct {
+	Image             string   `json:"image"`
+	CompatibleNodes   []string `json:"compatibleNodes"`
+	IncompatibleNodes []string `json:"incompatibleNodes"`
+	// CachedAt is when the scheduler last evaluated the image, if it is cached
+	CachedAt *time.Time `json:"cachedAt,omitempty"`
+}
+
+// serveSimulate evaluates the image given by the "image" query parameter
+// against all nodes without creating NodeFeatureGroups.
+func (f *ImageCompatibilityPlugin) serveSimulate(w http.ResponseWriter, r *http.Request) {
+	image := r.URL.Query().Get("image")
+	if image == "" {
+		http.Error(w, "missing image query parameter", http.StatusBadRequest)
+		return
+	}
+
+	compatible, incompatible, err := f.Simulate(r.Context(), image)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("simulate image %s failed: %v", image, err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := simulateResponse{
+		Image:             image,
+		CompatibleNodes:   compatible,
+		IncompatibleNodes: incompatible,
+	}
+	if entries := f.cacheEntries(image); len(entries) > 0 {
+		resp.CachedAt = &entries[0].CachedAt
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("Failed to write simulate response for image %s: %v", image, err)
+	}
+}
+
+// serveCache lists the cached image evaluations with when they were made, or
+// only the one of the image given by the optional "image" query parameter.
+func (f *ImageCompatibilityPlugin) serveCache(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(f.cacheEntries(r.URL.Query().Get("image"))); err != nil {
+		log.Printf("Failed to write cache response: %v", err)
+	}
+}
+
+// serveArtifacts lists the compatibility rules last fetched for images when
+// RetainLastArtifacts is set, or only those of the optional "image" query parameter.
+func (f *ImageCompatibilityPlugin) serveArtifacts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(f.artifactHistory.records(r.URL.Query().Get("image"))); err != nil {
+		log.Printf("Failed to write artifacts response: %v", err)
+	}
+}
+
+// serveDecisions lists the last compatibility results with their node features
+// when RetainLastDecisions is set, or only those of the optional "pod"
+// ("namespace/name") query parameter.
+func (f *ImageCompatibilityPlugin) serveDecisions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(f.decisions.list(r.URL.Query().Get("pod"))); err != nil {
+		log.Printf("Failed to write decisions response: %v", err)
+	}
+}
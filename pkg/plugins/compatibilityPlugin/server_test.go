@@ -0,0 +1,43 @@
+package compatibilityPlugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServeCache(t *testing.T) {
+	plugin := &ImageCompatibilityPlugin{imageToNFGCache: newNFGCache()}
+	before := time.Now()
+	plugin.updateCacheForImage("docker.io/library/app:v1", []string{"image-compat-app-x"})
+	plugin.updateCacheForImage("docker.io/library/plain:v1", nil)
+
+	rec := httptest.NewRecorder()
+	plugin.serveCache(rec, httptest.NewRequest(http.MethodGet, "/debug/cache?image=docker.io/library/app:v1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", rec.Code)
+	}
+
+	var entries []CacheEntry
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("invalid response %s: %v", rec.Body, err)
+	}
+	if len(entries) != 1 || entries[0].Image != "docker.io/library/app:v1" {
+		t.Fatalf("expected the app image entry only, got %+v", entries)
+	}
+	if entries[0].CachedAt.Before(before) {
+		t.Errorf("expected cachedAt after %v, got %v", before, entries[0].CachedAt)
+	}
+
+	rec = httptest.NewRecorder()
+	plugin.serveCache(rec, httptest.NewRequest(http.MethodGet, "/debug/cache", nil))
+	entries = nil
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("invalid response %s: %v", rec.Body, err)
+	}
+	if len(entries) != 2 || !entries[1].NoRequirements {
+		t.Errorf("expected both entries sorted by image, got %+v", entries)
+	}
+}
@@ -0,0 +1,266 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sclient "k8s.io/client-go/kubernetes"
+	nfdclientset "sigs.k8s.io/node-feature-discovery/api/generated/clientset/versioned"
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
+	"sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/nodefeaturerule"
+)
+
+// Simulator answers "which nodes could run this image" by evaluating the
+// image compatibility artifact against the NodeFeature objects published by
+// NFD in-process. It never creates NodeFeatureGroups or pods.
+type Simulator struct {
+	KubeClient k8sclient.Interface
+	NfdClient  nfdclientset.Interface
+	Args       ImageCompatibilityPluginArgs
+	// RuntimeClass selects the compatibility sets of a runtime class, the
+	// default sets are evaluated when empty.
+	RuntimeClass string
+	// NodeSelector is a label selector restricting the evaluated nodes, all
+	// nodes are evaluated when empty.
+	NodeSelector string
+	// Decide decides whether a node is compatible from its required
+	// compatibility sets, MatchRatioDecision with Args.MinMatchRatio when nil.
+	Decide DecisionFunc
+}
+
+// decision returns the DecisionFunc of the simulator.
+func (s *Simulator) decision() DecisionFunc {
+	if s.Decide != nil {
+		return s.Decide
+	}
+	return MatchRatioDecision(minMatchRatio(s.Args))
+}
+
+// Simulate evaluates the image against all nodes of the cluster using the
+// plugin's clients and configuration.
+func (f *ImageCompatibilityPlugin) Simulate(ctx context.Context, image string) (compatibleNodes, incompatibleNodes []string, err error) {
+	return f.simulate(ctx, image, "")
+}
+
+// simulate evaluates the image against the nodes matching the label selector.
+func (f *ImageCompatibilityPlugin) simulate(ctx context.Context, image, nodeSelector string) (compatibleNodes, incompatibleNodes []string, err error) {
+	s, resolved, err := f.simulator(ctx, image, nodeSelector)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.Simulate(ctx, resolved)
+}
+
+// simulateNode evaluates the image against a single node.
+func (f *ImageCompatibilityPlugin) simulateNode(ctx context.Context, image, nodeName string) (bool, error) {
+	s, resolved, err := f.simulator(ctx, image, "")
+	if err != nil {
+		return false, err
+	}
+	return s.SimulateNode(ctx, resolved, nodeName)
+}
+
+// simulateArchitectures evaluates the image variant of every architecture
+// against the nodes of that architecture matching the label selector.
+func (f *ImageCompatibilityPlugin) simulateArchitectures(ctx context.Context, image, nodeSelector string) ([]ArchitectureCompatibility, error) {
+	s, resolved, err := f.simulator(ctx, image, nodeSelector)
+	if err != nil {
+		return nil, err
+	}
+	return s.SimulateArchitectures(ctx, resolved)
+}
+
+// simulateTopologyDomains evaluates the image against each domain of the
+// topology key, among the nodes matching the label selector.
+func (f *ImageCompatibilityPlugin) simulateTopologyDomains(ctx context.Context, image, nodeSelector, topologyKey string) ([]DomainCompatibility, error) {
+	s, resolved, err := f.simulator(ctx, image, nodeSelector)
+	if err != nil {
+		return nil, err
+	}
+	return s.SimulateTopologyDomains(ctx, resolved, topologyKey)
+}
+
+// simulator returns a Simulator using the plugin's clients and configuration,
+// and the image resolved by the plugin's ImageResolver.
+func (f *ImageCompatibilityPlugin) simulator(ctx context.Context, image, nodeSelector string) (*Simulator, string, error) {
+	nfdCli, err := f.getNfdClient()
+	if err != nil {
+		return nil, "", err
+	}
+
+	resolved, err := f.resolveImage(ctx, nil, image)
+	if err != nil {
+		return nil, "", fmt.Errorf("resolve image %s failed: %w", image, err)
+	}
+	if resolved != image {
+		log.Printf("Resolved image %s to %s for simulation", image, resolved)
+	}
+
+	s := &Simulator{
+		KubeClient: f.handle.ClientSet(),
+		NfdClient:  nfdCli,
+		Args:       f.args,
+
+		NodeSelector: nodeSelector,
+		Decide:       f.decision(),
+	}
+	return s, resolved, nil
+}
+
+// Simulate returns the sorted names of nodes that are compatible and
+// incompatible with the image.
+func (s *Simulator) Simulate(ctx context.Context, image string) (compatibleNodes, incompatibleNodes []string, err error) {
+	groups, err := s.compatibilityGroups(ctx, image)
+	if err != nil {
+		return nil, nil, err
+	}
+	nodes, nodeFeatures, err := s.listNodes(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	compatibleNodes, incompatibleNodes = evaluateNodes(nodes, nodeFeatures, groups, s.decision())
+	return compatibleNodes, incompatibleNodes, nil
+}
+
+// SimulateNode reports whether the image is compatible with the node,
+// evaluating only that node and its NodeFeature objects.
+func (s *Simulator) SimulateNode(ctx context.Context, image, nodeName string) (bool, error) {
+	node, err := s.KubeClient.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to get node %s: %w", nodeName, err)
+	}
+	groups, err := s.compatibilityGroups(ctx, image)
+	if err != nil {
+		return false, err
+	}
+	nodeFeatures, err := s.NfdClient.NfdV1alpha1().NodeFeatures("").List(ctx, metav1.ListOptions{
+		LabelSelector: nfdv1alpha1.NodeFeatureObjNodeNameLabel + "=" + nodeName,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to list NodeFeatures of node %s: %w", nodeName, err)
+	}
+
+	compatibleNodes, _ := evaluateNodes([]v1.Node{*node}, nodeFeatures.Items, groups, s.decision())
+	return len(compatibleNodes) == 1, nil
+}
+
+// compatibilityGroups returns the compatibility sets of the image that apply
+// to the runtime class.
+func (s *Simulator) compatibilityGroups(ctx context.Context, image string) ([]nfdv1alpha1.NodeFeatureGroup, error) {
+	groups, err := fetchCompatibilityGroups(ctx, s.KubeClient, image, s.Args)
+	if err != nil {
+		return nil, err
+	}
+	return selectRuntimeClassGroups(groups, s.RuntimeClass), nil
+}
+
+// listNodes returns the selected nodes and all NodeFeature objects.
+func (s *Simulator) listNodes(ctx context.Context) ([]v1.Node, []nfdv1alpha1.NodeFeature, error) {
+	nodes, err := s.KubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: s.NodeSelector})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	nodeFeatures, err := s.NfdClient.NfdV1alpha1().NodeFeatures("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list NodeFeatures: %w", err)
+	}
+	return nodes.Items, nodeFeatures.Items, nil
+}
+
+// fetchCompatibilityGroups fetches the compatibility artifact of the image and
+// returns its rules as (not created) NodeFeatureGroups.
+func fetchCompatibilityGroups(ctx context.Context, kubeClient k8sclient.Interface, image string, args ImageCompatibilityPluginArgs) ([]nfdv1alpha1.NodeFeatureGroup, error) {
+	ac, err := newArtifactClient(ctx, kubeClient, image, args)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := NewFeatureGroupManagement(ac).WithFetchOptions(artifactFetchOptions(args)).TransferFromArtifact(ctx)
+	if err != nil {
+		if isImageNotFoundError(err) {
+			return nil, &ImageNotFoundError{Image: image, Err: err}
+		}
+		return nil, fmt.Errorf("failed to get compatibility rules for image %s: %w", image, err)
+	}
+	return groups, nil
+}
+
+// evaluateNodes splits nodes into those accepted by the decision function and
+// the others. As in the plugin, only the required groups are decided on, the
+// weighted ones are preferences for scoring. Nodes without NodeFeature
+// objects are never compatible.
+func evaluateNodes(nodes []v1.Node, nodeFeatures []nfdv1alpha1.NodeFeature, groups []nfdv1alpha1.NodeFeatureGroup, decide DecisionFunc) (compatibleNodes, incompatibleNodes []string) {
+	featuresByNode := mergeNodeFeatures(nodeFeatures)
+	required, _ := splitGroupsByWeight(groups)
+
+	for _, node := range nodes {
+		features, ok := featuresByNode[node.Name]
+		if ok && decide(node.Name, requiredSetStatuses(node.Name, features, required)) {
+			compatibleNodes = append(compatibleNodes, node.Name)
+		} else {
+			incompatibleNodes = append(incompatibleNodes, node.Name)
+		}
+	}
+
+	slices.Sort(compatibleNodes)
+	slices.Sort(incompatibleNodes)
+	return compatibleNodes, incompatibleNodes
+}
+
+// mergeNodeFeatures merges all NodeFeature objects of each node into a single
+// feature set, in the same order as nfd-master (sorted by name).
+func mergeNodeFeatures(nodeFeatures []nfdv1alpha1.NodeFeature) map[string]*nfdv1alpha1.Features {
+	sorted := slices.Clone(nodeFeatures)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Name != sorted[j].Name {
+			return sorted[i].Name < sorted[j].Name
+		}
+		return sorted[i].Namespace < sorted[j].Namespace
+	})
+
+	merged := make(map[string]*nfdv1alpha1.Features)
+	for i := range sorted {
+		nodeName := sorted[i].Labels[nfdv1alpha1.NodeFeatureObjNodeNameLabel]
+		if nodeName == "" {
+			continue
+		}
+		features, ok := merged[nodeName]
+		if !ok {
+			features = nfdv1alpha1.NewFeatures()
+			merged[nodeName] = features
+		}
+		sorted[i].Spec.Features.MergeInto(features)
+	}
+	return merged
+}
+
+// matchesAllGroups reports whether the features match every group. As in
+// nfd-master, a group matches when any of its rules matches, and the vars of
+// each rule are fed back for the subsequent rules.
+func matchesAllGroups(nodeName string, features *nfdv1alpha1.Features, groups []nfdv1alpha1.NodeFeatureGroup) bool {
+	for _, group := range groups {
+		groupFeatures := features.DeepCopy()
+		matched := false
+		for _, rule := range group.Spec.Rules {
+			out, err := nodefeaturerule.ExecuteGroupRule(&rule, groupFeatures, true)
+			if err != nil {
+				log.Printf("Failed to evaluate rule %q for node %s: %v", rule.Name, nodeName, err)
+				continue
+			}
+			if out.MatchStatus.IsMatch {
+				matched = true
+				break
+			}
+			groupFeatures.InsertAttributeFeatures(nfdv1alpha1.RuleBackrefDomain, nfdv1alpha1.RuleBackrefFeature, out.Vars)
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package compatibilityPlugin
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
+)
+
+// vendorNodeFeature returns the NodeFeature of a node with a CPU of the vendor.
+func vendorNodeFeature(node, vendor string) nfdv1alpha1.NodeFeature {
+	features := nfdv1alpha1.NewFeatures()
+	features.Attributes["cpu.model"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"vendor_id": vendor})
+	return nfdv1alpha1.NodeFeature{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   node,
+			Labels: map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: node},
+		},
+		Spec: nfdv1alpha1.NodeFeatureSpec{Features: *features},
+	}
+}
+
+// vendorGroup returns a compatibility set requiring a CPU of the vendor.
+func vendorGroup(vendor string) nfdv1alpha1.NodeFeatureGroup {
+	return nfdv1alpha1.NodeFeatureGroup{
+		Spec: nfdv1alpha1.NodeFeatureGroupSpec{
+			Rules: []nfdv1alpha1.GroupRule{{
+				Name: "cpu vendor",
+				MatchFeatures: nfdv1alpha1.FeatureMatcher{{
+					Feature: "cpu.model",
+					MatchExpressions: &nfdv1alpha1.MatchExpressionSet{
+						"vendor_id": {Op: nfdv1alpha1.MatchIn, Value: nfdv1alpha1.MatchValue{vendor}},
+					},
+				}},
+			}},
+		},
+	}
+}
+
+func TestEvaluateNodes(t *testing.T) {
+	nodes := []v1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "intel-node"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "amd-node"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "no-nfd-node"}},
+	}
+	nodeFeatures := []nfdv1alpha1.NodeFeature{
+		vendorNodeFeature("intel-node", "Intel"),
+		vendorNodeFeature("amd-node", "AMD"),
+	}
+
+	compatible, incompatible := evaluateNodes(nodes, nodeFeatures, []nfdv1alpha1.NodeFeatureGroup{vendorGroup("Intel")}, MatchRatioDecision(1))
+	if want := []string{"intel-node"}; !reflect.DeepEqual(compatible, want) {
+		t.Errorf("compatible = %v, want %v", compatible, want)
+	}
+	if want := []string{"amd-node", "no-nfd-node"}; !reflect.DeepEqual(incompatible, want) {
+		t.Errorf("incompatible = %v, want %v", incompatible, want)
+	}
+
+	// Every group must match
+	compatible, _ = evaluateNodes(nodes, nodeFeatures, []nfdv1alpha1.NodeFeatureGroup{vendorGroup("Intel"), vendorGroup("AMD")}, MatchRatioDecision(1))
+	if len(compatible) != 0 {
+		t.Errorf("expected no compatible nodes when groups conflict, got %v", compatible)
+	}
+}
+
+func TestEvaluateNodesDecision(t *testing.T) {
+	nodes := []v1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "intel-node"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "amd-node"}},
+	}
+	nodeFeatures := []nfdv1alpha1.NodeFeature{
+		vendorNodeFeature("intel-node", "Intel"),
+		vendorNodeFeature("amd-node", "AMD"),
+	}
+
+	// A weighted set is a preference for scoring and does not filter
+	preferred := vendorGroup("AMD")
+	preferred.Annotations = map[string]string{NFGWeightAnnotation: "10"}
+	compatible, _ := evaluateNodes(nodes, nodeFeatures, []nfdv1alpha1.NodeFeatureGroup{vendorGroup("Intel"), preferred}, MatchRatioDecision(1))
+	if want := []string{"intel-node"}; !reflect.DeepEqual(compatible, want) {
+		t.Errorf("compatible = %v, want %v with a weighted set", compatible, want)
+	}
+
+	// Half of the required sets are enough with a ratio of 0.5
+	compatible, _ = evaluateNodes(nodes, nodeFeatures, []nfdv1alpha1.NodeFeatureGroup{vendorGroup("Intel"), vendorGroup("AMD")}, MatchRatioDecision(0.5))
+	if want := []string{"amd-node", "intel-node"}; !reflect.DeepEqual(compatible, want) {
+		t.Errorf("compatible = %v, want %v with a ratio of 0.5", compatible, want)
+	}
+
+	// The simulator defaults to the configured MinMatchRatio
+	ratio := 0.5
+	s := &Simulator{Args: ImageCompatibilityPluginArgs{MinMatchRatio: &ratio}}
+	compatible, _ = evaluateNodes(nodes, nodeFeatures, []nfdv1alpha1.NodeFeatureGroup{vendorGroup("Intel"), vendorGroup("AMD")}, s.decision())
+	if len(compatible) != 2 {
+		t.Errorf("expected both nodes compatible with MinMatchRatio 0.5, got %v", compatible)
+	}
+}
@@ -0,0 +1,88 @@
+package compatibilityPlugin
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// DefaultMaxReasonLength is the default maximum length of the status reasons
+// of the plugin, which end up in pod conditions and events.
+const DefaultMaxReasonLength = 1024
+
+// reasonSeparator separates the parts of a reason, as in wrapped errors.
+const reasonSeparator = ": "
+
+// maxReasonLength returns the configured MaxReasonLength, or the default.
+func (f *ImageCompatibilityPlugin) maxReasonLength() int {
+	if f.args.MaxReasonLength == 0 {
+		return DefaultMaxReasonLength
+	}
+	return f.args.MaxReasonLength
+}
+
+// rejectionReasonData holds the fields available to RejectionReasonTemplate.
+type rejectionReasonData struct {
+	Node string
+	// Pod is the "namespace/name" of the pod
+	Pod string
+	// Phases are the container phases whose images the node fails, empty
+	// when the phase is not known
+	Phases []string
+	// Reason is the default reason
+	Reason string
+}
+
+// parseRejectionReasonTemplate parses a RejectionReasonTemplate.
+func parseRejectionReasonTemplate(text string) (*template.Template, error) {
+	return template.New("rejectionReason").Option("missingkey=error").Parse(text)
+}
+
+// rejectionReason returns the reason of rejecting an incompatible node,
+// rendered with RejectionReasonTemplate when set. The default reason is used
+// when the template fails.
+func (f *ImageCompatibilityPlugin) rejectionReason(pod *v1.Pod, nodeName string, phases []string, reason string) string {
+	if f.reasonTemplate == nil {
+		return reason
+	}
+	data := rejectionReasonData{Node: nodeName, Pod: pod.Namespace + "/" + pod.Name, Phases: phases, Reason: reason}
+	var buf bytes.Buffer
+	if err := f.reasonTemplate.Execute(&buf, data); err != nil {
+		log.Printf("Failed to render the rejection reason of node %s for pod %s, using the default: %v", nodeName, data.Pod, err)
+		return reason
+	}
+	return truncateReason(buf.String(), f.maxReasonLength())
+}
+
+// truncateReason shortens reason to at most maxLen bytes. It cuts at a
+// boundary between the parts of the reason and reports how many parts were
+// dropped, so that the message stays readable. A first part that does not
+// fit on its own is cut at a character boundary.
+func truncateReason(reason string, maxLen int) string {
+	if len(reason) <= maxLen {
+		return reason
+	}
+
+	parts := strings.Split(reason, reasonSeparator)
+	for kept := len(parts) - 1; kept > 0; kept-- {
+		truncated := strings.Join(parts[:kept], reasonSeparator) + fmt.Sprintf(" (%d more)", len(parts)-kept)
+		if len(truncated) <= maxLen {
+			return truncated
+		}
+	}
+
+	const ellipsis = "..."
+	if maxLen <= len(ellipsis) {
+		return ellipsis[:max(maxLen, 0)]
+	}
+	cut := maxLen - len(ellipsis)
+	for cut > 0 && !utf8.RuneStart(reason[cut]) {
+		cut--
+	}
+	return reason[:cut] + ellipsis
+}
@@ -0,0 +1,60 @@
+package compatibilityPlugin
+
+import (
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTruncateReason(t *testing.T) {
+	reason := "failed to create NodeFeatureGroups: failed to fetch compatibility spec: GET https://registry.example.com/v2/app/referrers: response status code 500"
+
+	if got := truncateReason(reason, len(reason)); got != reason {
+		t.Errorf("expected reason within the limit to be kept, got %q", got)
+	}
+
+	got := truncateReason(reason, 80)
+	if want := "failed to create NodeFeatureGroups: failed to fetch compatibility spec (2 more)"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	got = truncateReason(strings.Repeat("é", 20), 10)
+	if len(got) > 10 || !strings.HasSuffix(got, "...") || !strings.HasPrefix(got, "éé") {
+		t.Errorf("unexpected truncation of a single part: %q", got)
+	}
+}
+
+func TestRejectionReason(t *testing.T) {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-a"}}
+	reason := "node n1 is not compatible with the init container images"
+
+	plugin := &ImageCompatibilityPlugin{}
+	if got := plugin.rejectionReason(pod, "n1", []string{ContainerPhaseInit}, reason); got != reason {
+		t.Errorf("expected the default reason without a template, got %q", got)
+	}
+
+	tmpl, err := parseRejectionReasonTemplate(`{{.Reason}} ({{join .Phases ","}}), see https://runbooks.example.com/image-compat?pod={{.Pod}}&node={{.Node}}`)
+	if err == nil {
+		t.Fatalf("expected undefined functions to be rejected at parse time")
+	}
+	tmpl, err = parseRejectionReasonTemplate(`{{.Reason}}, see https://runbooks.example.com/image-compat?pod={{.Pod}}&node={{.Node}}`)
+	if err != nil {
+		t.Fatalf("parseRejectionReasonTemplate: %v", err)
+	}
+	plugin.reasonTemplate = tmpl
+	want := reason + ", see https://runbooks.example.com/image-compat?pod=team-a/app&node=n1"
+	if got := plugin.rejectionReason(pod, "n1", []string{ContainerPhaseInit}, reason); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	// Execution errors fall back to the default reason
+	plugin.reasonTemplate, err = parseRejectionReasonTemplate(`{{index .Phases 3}}`)
+	if err != nil {
+		t.Fatalf("parseRejectionReasonTemplate: %v", err)
+	}
+	if got := plugin.rejectionReason(pod, "n1", nil, reason); got != reason {
+		t.Errorf("expected the default reason when the template fails, got %q", got)
+	}
+}
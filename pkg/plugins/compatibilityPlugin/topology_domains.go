@@ -0,0 +1,73 @@
+package compatibilityPlugin
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
+)
+
+// DomainCompatibility is the compatibility of an image with a topology
+// domain, e.g. a zone, as a whole.
+type DomainCompatibility struct {
+	Domain string `json:"domain"`
+	// Compatible is set when the decision function accepts the domain, a
+	// required compatibility set counting as matched when at least one node
+	// of the domain satisfies it
+	Compatible bool     `json:"compatible"`
+	Nodes      []string `json:"nodes"`
+}
+
+// SimulateTopologyDomains evaluates the image against the union of the
+// features of the selected nodes in each domain of the topology key, e.g.
+// topology.kubernetes.io/zone, for placement decisions above single nodes.
+// The union is taken set by set: a domain can run the image when each of its
+// compatibility sets is satisfied by some node of the domain, so attributes
+// of different nodes are never combined within a set. Nodes without the
+// topology label are left out.
+func (s *Simulator) SimulateTopologyDomains(ctx context.Context, image, topologyKey string) ([]DomainCompatibility, error) {
+	groups, err := s.compatibilityGroups(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	nodes, nodeFeatures, err := s.listNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return evaluateDomains(nodes, nodeFeatures, groups, topologyKey, s.decision()), nil
+}
+
+// evaluateDomains groups the nodes by the value of their topology label and
+// evaluates each domain, sorted by domain. Weighted groups are preferences
+// and do not affect the result.
+func evaluateDomains(nodes []v1.Node, nodeFeatures []nfdv1alpha1.NodeFeature, groups []nfdv1alpha1.NodeFeatureGroup, topologyKey string, decide DecisionFunc) []DomainCompatibility {
+	featuresByNode := mergeNodeFeatures(nodeFeatures)
+	required, _ := splitGroupsByWeight(groups)
+
+	nodesByDomain := make(map[string][]string)
+	for _, node := range nodes {
+		if domain, ok := node.Labels[topologyKey]; ok {
+			nodesByDomain[domain] = append(nodesByDomain[domain], node.Name)
+		}
+	}
+
+	results := make([]DomainCompatibility, 0, len(nodesByDomain))
+	for domain, domainNodes := range nodesByDomain {
+		slices.Sort(domainNodes)
+		sets := make([]CompatibilitySetStatus, len(required))
+		for i := range required {
+			sets[i] = CompatibilitySetStatus{
+				Tag: required[i].Annotations[NFGTagAnnotation],
+				Matched: slices.ContainsFunc(domainNodes, func(nodeName string) bool {
+					features, ok := featuresByNode[nodeName]
+					return ok && matchesAllGroups(nodeName, features, required[i:i+1])
+				}),
+			}
+		}
+		results = append(results, DomainCompatibility{Domain: domain, Nodes: domainNodes, Compatible: decide(domain, sets)})
+	}
+	slices.SortFunc(results, func(a, b DomainCompatibility) int { return strings.Compare(a.Domain, b.Domain) })
+	return results
+}
@@ -0,0 +1,43 @@
+package compatibilityPlugin
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
+)
+
+func TestEvaluateDomains(t *testing.T) {
+	zoneNode := func(name, zone string) v1.Node {
+		node := v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}}}
+		if zone != "" {
+			node.Labels[v1.LabelTopologyZone] = zone
+		}
+		return node
+	}
+	nodes := []v1.Node{
+		zoneNode("a-intel", "zone-a"),
+		zoneNode("a-amd", "zone-a"),
+		zoneNode("b-amd", "zone-b"),
+		zoneNode("unzoned", ""),
+	}
+	nodeFeatures := []nfdv1alpha1.NodeFeature{
+		vendorNodeFeature("a-intel", "Intel"),
+		vendorNodeFeature("a-amd", "AMD"),
+		vendorNodeFeature("b-amd", "AMD"),
+		vendorNodeFeature("unzoned", "Intel"),
+	}
+
+	// No single node satisfies both sets, but zone-a has a node for each
+	groups := []nfdv1alpha1.NodeFeatureGroup{vendorGroup("Intel"), vendorGroup("AMD")}
+	got := evaluateDomains(nodes, nodeFeatures, groups, v1.LabelTopologyZone, MatchRatioDecision(1))
+	want := []DomainCompatibility{
+		{Domain: "zone-a", Compatible: true, Nodes: []string{"a-amd", "a-intel"}},
+		{Domain: "zone-b", Compatible: false, Nodes: []string{"b-amd"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected domains %+v, got %+v", want, got)
+	}
+}
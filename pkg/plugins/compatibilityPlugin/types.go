@@ -1,12 +1,21 @@
 package compatibilityPlugin
 
 import (
+	"context"
+	"fmt"
+	"maps"
 	"sync"
+	"text/template"
 	"time"
 
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
 	fwk "k8s.io/kube-scheduler/framework"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/parallelize"
 	nfdclientset "sigs.k8s.io/node-feature-discovery/api/generated/clientset/versioned"
+	nfdlisters "sigs.k8s.io/node-feature-discovery/api/generated/listers/nfd/v1alpha1"
 	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/api/nfd/v1alpha1"
 )
 
@@ -19,23 +28,275 @@ const (
 	NfdMasterLabelSelector = "app.kubernetes.io/name=node-feature-discovery,role=master"
 	// NfdMasterLabelSelectorAlt is an alternative label selector for nfd-master pods.
 	NfdMasterLabelSelectorAlt = "app=nfd-master"
+	// NodeFeatureGroupResource is the NFD resource the plugin depends on.
+	NodeFeatureGroupResource = "nodefeaturegroups"
+	// NodeFeatureGroupEventResource identifies NodeFeatureGroup events in the
+	// scheduling queue, in the <resource>.<version>.<group> format.
+	NodeFeatureGroupEventResource fwk.EventResource = NodeFeatureGroupResource + ".v1alpha1.nfd.k8s-sigs.io"
 	// NfdUpdateGracePeriod is the grace period for NFD updates.
 	NfdUpdateGracePeriod = 3 * time.Second
+	// DefaultArtifactFetchTimeout bounds each compatibility artifact fetch attempt.
+	DefaultArtifactFetchTimeout = 10 * time.Second
+	// DefaultArtifactFetchAttempts is the number of fetch attempts on transient registry errors.
+	DefaultArtifactFetchAttempts = 3
+	// ArtifactFetchBackoff is the delay before the first fetch retry, doubled on every retry.
+	ArtifactFetchBackoff = 500 * time.Millisecond
+	// DefaultNFGCreateConcurrency is the number of NodeFeatureGroups of an
+	// artifact created concurrently.
+	DefaultNFGCreateConcurrency = 4
 )
 
 // ImageCompatibilityPlugin is the main image compatibility filter plugin.
 type ImageCompatibilityPlugin struct {
-	handle               framework.Handle
-	nfdClient            nfdclientset.Interface
-	nfdMasterNamespace   string
-	args                 ImageCompatibilityPluginArgs
-	imageToNFGCache      map[string][]string // Cache: image -> list of NFG names
-	imageToNFGCacheMutex sync.RWMutex        // Mutex to protect cache access
+	handle                    framework.Handle
+	parallelizer              parallelize.Parallelizer
+	nfdClient                 nfdclientset.Interface
+	nfdClientMutex            sync.RWMutex         // Mutex to protect lazy nfd client creation
+	nfgUnavailable            error                // Set when the cluster does not serve NodeFeatureGroups
+	nodeVerdictLocks          nodeLocks            // Per-node mutexes to serialize node verdict label patches
+	instanceTypeFeatures      instanceTypeFeatures // Cache: parsed instance type features ConfigMap
+	instanceTypeFeaturesMutex sync.Mutex           // Mutex to protect instance type features cache
+	nfdMasterNamespace        string
+	args                      ImageCompatibilityPluginArgs
+	imageResolver             ImageResolver
+	health                    *healthTracker
+	artifactHistory           *artifactHistory
+	customFeatures            *customFeatureSource
+	resultWebhook             *resultWebhook
+	nodeVerdicts              *nodeVerdictQueue
+	imageToNFGCache           *nfgCache    // Cache: image -> NFG names
+	imageToGroupsCache        *groupCache  // Cache: image -> compatibility sets evaluated in-process
+	tagDigests                *digestIndex // Cache: image tag -> digest reference
+	requiredNFGs              *requiredNFGIndex
+	decide                    DecisionFunc
+	ownerReferences           ownerReferencesFunc
+	reasonTemplate            *template.Template
+	decisions                 *decisionHistory
+	nodeFeatures              nfdlisters.NodeFeatureLister // NodeFeatures snapshotted with decisions
+	nodeFeaturesSynced        cache.InformerSynced
+	digestResolver            func(ctx context.Context, image string) (string, error)
+	fetchGroups               func(ctx context.Context, image string) ([]nfdv1alpha1.NodeFeatureGroup, error)
+}
+
+// nfgCacheEntry holds the NFG names created for an image and when they
+// stop being reused.
+type nfgCacheEntry struct {
+	nfgNames       []string
+	noRequirements bool      // The image has no compatibility requirements, no NFGs are needed
+	cachedAt       time.Time // When the image was evaluated
+	expiresAt      time.Time // Zero means the entry never expires
+}
+
+// CacheEntry describes a cached image evaluation for debugging.
+type CacheEntry struct {
+	Image             string     `json:"image"`
+	NodeFeatureGroups []string   `json:"nodeFeatureGroups,omitempty"`
+	NoRequirements    bool       `json:"noRequirements,omitempty"`
+	CachedAt          time.Time  `json:"cachedAt"`
+	ExpiresAt         *time.Time `json:"expiresAt,omitempty"`
 }
 
 // ImageCompatibilityPluginArgs holds the arguments for the ImageCompatibilityPlugin.
 type ImageCompatibilityPluginArgs struct {
 	PlainHttp bool `json:"plainHttp,omitempty"`
+	// CacheTTL is how long cached NFGs are reused for images whose tag does
+	// not match any of CacheTTLOverrides. Zero means no expiry.
+	CacheTTL metav1.Duration `json:"cacheTTL,omitempty"`
+	// CacheTTLOverrides sets per-tag cache TTLs. The first matching pattern wins.
+	CacheTTLOverrides []CacheTTLOverride `json:"cacheTTLOverrides,omitempty"`
+	// CacheFile, when set, persists the image evaluation cache to this file so
+	// it survives scheduler restarts. It is loaded at startup and written every
+	// CacheFlushInterval (default 1m) and on shutdown.
+	CacheFile          string          `json:"cacheFile,omitempty"`
+	CacheFlushInterval metav1.Duration `json:"cacheFlushInterval,omitempty"`
+	// RetryMissingImages reports images missing from their registry as Unschedulable
+	// so the pod is retried later (e.g. when the image is pushed after the pod is
+	// created), instead of UnschedulableAndUnresolvable.
+	RetryMissingImages bool `json:"retryMissingImages,omitempty"`
+	// RegistryPullSecrets maps registry hosts to "namespace/name" references of
+	// docker config secrets used to fetch compatibility artifacts from them.
+	RegistryPullSecrets map[string]string `json:"registryPullSecrets,omitempty"`
+	// BindAddress, when set, serves the plugin readiness (/readyz), liveness
+	// (/healthz) and debug (/debug/...) endpoints on this address, e.g. ":10260".
+	BindAddress string `json:"bindAddress,omitempty"`
+	// GRPCBindAddress, when set, serves the compatibility gRPC API defined in
+	// compatibility.proto on this address, e.g. ":10261".
+	GRPCBindAddress string `json:"grpcBindAddress,omitempty"`
+	// HealthFailureThreshold is the number of consecutive infrastructure failures
+	// after which /readyz reports not ready. Defaults to DefaultHealthFailureThreshold.
+	HealthFailureThreshold int `json:"healthFailureThreshold,omitempty"`
+	// ImageRewrites rewrites image reference prefixes before evaluation, mirroring
+	// admission policies that mutate images after the scheduler's view.
+	// The first matching rule wins. Ignored when a custom ImageResolver is plugged in.
+	ImageRewrites []ImageRewrite `json:"imageRewrites,omitempty"`
+	// NormalizeImageReferences expands image references to their canonical
+	// form (docker.io registry, library/ namespace and latest tag) before and
+	// after ImageRewrites, so that equivalent references share cache entries
+	// and artifacts. Rewrite prefixes must then match the canonical form.
+	NormalizeImageReferences bool `json:"normalizeImageReferences,omitempty"`
+	// SkipLocalImages leaves images only present in the node's container
+	// runtime out of the evaluation: images without a registry host, e.g.
+	// "app:v1", and images starting with one of LocalImagePrefixes.
+	SkipLocalImages    bool     `json:"skipLocalImages,omitempty"`
+	LocalImagePrefixes []string `json:"localImagePrefixes,omitempty"`
+	// DedupeByDigest resolves image tags to their manifest digest and caches
+	// NFGs by digest, so that tags of the same image are evaluated once. The
+	// tag to digest mapping is cached with the TTL of the tag.
+	DedupeByDigest bool `json:"dedupeByDigest,omitempty"`
+	// UseExistingNodeFeatureGroups uses the NodeFeatureGroups labelled with
+	// NFGImageHashLabel for an image instead of creating groups from its
+	// compatibility artifact. Groups are only created for images without any.
+	UseExistingNodeFeatureGroups bool `json:"useExistingNodeFeatureGroups,omitempty"`
+	// PullThroughCaches lists the pull-through caches images are pulled from.
+	// The compatibility artifact of a cached image is fetched from its origin
+	// registry when the cache does not have it.
+	PullThroughCaches []PullThroughCache `json:"pullThroughCaches,omitempty"`
+	// CheckImageVolumes evaluates the images of OCI image volumes along with the
+	// container images. Defaults to true.
+	CheckImageVolumes *bool `json:"checkImageVolumes,omitempty"`
+	// CheckSandboxImage evaluates the sandbox (pause) image of the pod runtime
+	// along with the main container images.
+	CheckSandboxImage bool `json:"checkSandboxImage,omitempty"`
+	// SandboxImage is the sandbox image of the default runtime, e.g.
+	// "registry.k8s.io/pause:3.10".
+	SandboxImage string `json:"sandboxImage,omitempty"`
+	// RuntimeClassSandboxImages overrides SandboxImage for pods of a runtime class.
+	RuntimeClassSandboxImages map[string]string `json:"runtimeClassSandboxImages,omitempty"`
+	// MaxReasonLength bounds the length of the status reasons reported to the
+	// scheduler. Longer reasons are cut between parts with a "(N more)" suffix.
+	// Defaults to DefaultMaxReasonLength.
+	MaxReasonLength int `json:"maxReasonLength,omitempty"`
+	// RejectionReasonTemplate renders the reason of rejecting an incompatible
+	// node as a text/template over .Node, .Pod, .Phases and the default
+	// .Reason, e.g. to link a runbook. The default reason is used when empty.
+	RejectionReasonTemplate string `json:"rejectionReasonTemplate,omitempty"`
+	// OfflineArtifactDir is a directory of offline compatibility artifacts for
+	// air-gapped clusters. Each YAML or JSON file holds a compatibility spec
+	// with an additional "image" field naming the image it belongs to.
+	OfflineArtifactDir string `json:"offlineArtifactDir,omitempty"`
+	// OfflineArtifactConfigMap is the "namespace/name" reference of a ConfigMap
+	// whose values are offline compatibility artifacts, in the same format as
+	// the files of OfflineArtifactDir. Images missing from the offline
+	// artifacts are fetched from the registry.
+	OfflineArtifactConfigMap string `json:"offlineArtifactConfigMap,omitempty"`
+	// ArtifactFetchTimeout bounds each compatibility artifact fetch attempt.
+	// Defaults to DefaultArtifactFetchTimeout.
+	ArtifactFetchTimeout metav1.Duration `json:"artifactFetchTimeout,omitempty"`
+	// ArtifactFetchAttempts is the maximum number of artifact fetch attempts on
+	// transient registry errors. Defaults to DefaultArtifactFetchAttempts.
+	ArtifactFetchAttempts int `json:"artifactFetchAttempts,omitempty"`
+	// NFGCreateConcurrency is the number of NodeFeatureGroups of an artifact
+	// created concurrently. Defaults to DefaultNFGCreateConcurrency.
+	NFGCreateConcurrency int `json:"nfgCreateConcurrency,omitempty"`
+	// NodeVerdictLabels labels the evaluated nodes with the last compatibility
+	// verdict of each image, e.g. "image-compat.scheduler/<image-hash>: compatible".
+	NodeVerdictLabels bool `json:"nodeVerdictLabels,omitempty"`
+	// MaxNodeVerdictLabels bounds the number of verdict labels per node, the
+	// oldest are removed first. Defaults to DefaultMaxNodeVerdictLabels.
+	MaxNodeVerdictLabels int `json:"maxNodeVerdictLabels,omitempty"`
+	// RevalidationAnnotation is a node annotation, e.g. set on driver
+	// upgrades, whose changes re-evaluate the verdict labels of the node for
+	// the images whose NodeFeatureGroups are cached. Requires NodeVerdictLabels.
+	RevalidationAnnotation string `json:"revalidationAnnotation,omitempty"`
+	// InstanceTypeFeaturesConfigMap is the "namespace/name" reference of a
+	// ConfigMap mapping node instance types (the node.kubernetes.io/instance-type
+	// label) to NFD feature sets. When every node has a mapped instance type,
+	// images are evaluated against the mapping in-process, skipping NFD.
+	InstanceTypeFeaturesConfigMap string `json:"instanceTypeFeaturesConfigMap,omitempty"`
+	// ResultLogFormat is the format of the per-pod compatibility result log,
+	// "text" (default) or "json" for a single JSON line.
+	ResultLogFormat string `json:"resultLogFormat,omitempty"`
+	// ResultOwners reports the top-level controller of each pod, such as its
+	// Deployment or CronJob, with the compatibility results.
+	ResultOwners bool `json:"resultOwners,omitempty"`
+	// ArtifactReferenceTemplate derives the reference of a compatibility artifact
+	// published separately from the image, as a text/template over the image
+	// .Registry, .Repository, .Tag and .Digest, e.g.
+	// "{{.Registry}}/{{.Repository}}:{{.Tag}}-compat". The image referrers are
+	// used when the derived artifact does not exist.
+	ArtifactReferenceTemplate string `json:"artifactReferenceTemplate,omitempty"`
+	// MinMatchRatio is the minimum ratio (0.0-1.0) of the required compatibility
+	// sets of a pod's images that a node has to satisfy to be compatible.
+	// Defaults to 1, all sets.
+	MinMatchRatio *float64 `json:"minMatchRatio,omitempty"`
+	// PerImageTimeout bounds fetching the compatibility artifact of an image
+	// and creating its NodeFeatureGroups, including fetch retries. Images are
+	// processed concurrently, each with its own budget. Zero means no timeout.
+	PerImageTimeout metav1.Duration `json:"perImageTimeout,omitempty"`
+	// PreFilterTimeout bounds the whole evaluation of a pod, all of its images
+	// and waiting for nfd-master to fill in the NodeFeatureGroup status, and
+	// caps PerImageTimeout. Zero means no timeout.
+	PreFilterTimeout metav1.Duration `json:"preFilterTimeout,omitempty"`
+	// RetainLastArtifacts keeps the compatibility rules fetched for the last N
+	// images in memory, served at /debug/artifacts. Zero disables it.
+	RetainLastArtifacts int `json:"retainLastArtifacts,omitempty"`
+	// RetainLastDecisions keeps the last N compatibility results in memory with
+	// the node features they were computed from, served at /debug/decisions.
+	// Zero disables it.
+	RetainLastDecisions int `json:"retainLastDecisions,omitempty"`
+	// CustomFeaturesConfigMap is the "namespace/name" reference of a ConfigMap
+	// with manually tracked node features, in the NodeFeature spec.features
+	// format. When set, images are evaluated in-process against the NFD features
+	// merged with these.
+	CustomFeaturesConfigMap string `json:"customFeaturesConfigMap,omitempty"`
+	// CustomFeaturesKeyLabel is the node label whose value keys the custom
+	// features ConfigMap data. Defaults to keying by node name.
+	CustomFeaturesKeyLabel string `json:"customFeaturesKeyLabel,omitempty"`
+	// ArtifactInheritance merges the compatibility artifacts of the base
+	// images named by the org.opencontainers.image.base.name manifest
+	// annotation into the requirements of an image. Off by default, as it
+	// costs a manifest fetch per base image.
+	ArtifactInheritance bool `json:"artifactInheritance,omitempty"`
+	// ResultWebhookURL is the endpoint every compatibility result is posted to
+	// as JSON, from a background queue. Disabled when empty.
+	ResultWebhookURL string `json:"resultWebhookURL,omitempty"`
+	// ResultWebhookTimeout bounds each post. Defaults to 5s.
+	ResultWebhookTimeout metav1.Duration `json:"resultWebhookTimeout,omitempty"`
+	// ResultWebhookAuthSecret is the "namespace/name" reference of a Secret
+	// whose "authorization" key is sent as the Authorization header.
+	ResultWebhookAuthSecret string `json:"resultWebhookAuthSecret,omitempty"`
+}
+
+// ImageRewrite replaces the From prefix of an image reference with To.
+type ImageRewrite struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// PullThroughCache maps the image prefix of a pull-through cache to the
+// prefix of the origin registry it mirrors, e.g. "cache.example.com/dockerhub/"
+// to "docker.io/".
+type PullThroughCache struct {
+	Cache  string `json:"cache"`
+	Origin string `json:"origin"`
+}
+
+// ImageNotFoundError is returned when an image or its repository does not
+// exist in the registry, so that no node can ever run it.
+type ImageNotFoundError struct {
+	Image string
+	Err   error
+}
+
+func (e *ImageNotFoundError) Error() string {
+	return fmt.Sprintf("image %s not found in registry: %v", e.Image, e.Err)
+}
+
+func (e *ImageNotFoundError) Unwrap() error {
+	return e.Err
+}
+
+// ImageResolver returns the effective image reference that will be pulled
+// for a container image of the pod.
+type ImageResolver func(ctx context.Context, pod *v1.Pod, image string) (string, error)
+
+// CacheTTLOverride maps image tags matching TagPattern to a cache TTL.
+type CacheTTLOverride struct {
+	// TagPattern is a shell pattern (see path.Match) matched against the image tag,
+	// e.g. "nightly", "dev-*" or "v*.*.*".
+	TagPattern string `json:"tagPattern"`
+	// TTL is how long cached NFGs are reused for matching images. Zero means no expiry.
+	TTL metav1.Duration `json:"ttl"`
 }
 
 type Compatibility struct {
@@ -53,8 +314,12 @@ type Compatibility struct {
 // the images of a Pod within a single scheduling cycle.
 type CompatibilityState struct {
 	CompatibleNodes map[string]struct{}
-	CreatedNFGs     []string // Names of created NodeFeatureGroup CRs
-	Namespace       string   // Namespace where NFGs were created
+	CreatedNFGs     []string         // Names of created NodeFeatureGroup CRs
+	Namespace       string           // Namespace where NFGs were created
+	NodeScores      map[string]int64 // Scores from the preferred compatibility sets
+	// IncompatiblePhases lists the container phases whose images each
+	// incompatible node fails, init before main.
+	IncompatiblePhases map[string][]string
 }
 
 // Clone implements the scheduler framework StateData interface.
@@ -78,10 +343,15 @@ func (s *CompatibilityState) Clone() fwk.StateData {
 		CompatibleNodes: newMap,
 		CreatedNFGs:     newCreatedNFGs,
 		Namespace:       s.Namespace,
+		NodeScores:      maps.Clone(s.NodeScores),
+		// The phase lists are never modified after PreFilter
+		IncompatiblePhases: maps.Clone(s.IncompatiblePhases),
 	}
 }
 
 var (
-	_ framework.FilterPlugin    = &ImageCompatibilityPlugin{}
-	_ framework.PreFilterPlugin = &ImageCompatibilityPlugin{}
+	_ framework.FilterPlugin      = &ImageCompatibilityPlugin{}
+	_ framework.PreFilterPlugin   = &ImageCompatibilityPlugin{}
+	_ framework.EnqueueExtensions = &ImageCompatibilityPlugin{}
+	_ framework.ScorePlugin       = &ImageCompatibilityPlugin{}
 )
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"custom-scheduler/pkg/plugins/compatibilityPlugin"
+
+	"github.com/spf13/cobra"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// schedulerPolicyRules are the rules kube-scheduler itself needs, for its
+// informers, binding, events and leader election.
+var schedulerPolicyRules = []rbacv1.PolicyRule{
+	{APIGroups: []string{""}, Resources: []string{"pods", "nodes", "namespaces", "endpoints"}, Verbs: []string{"get", "list", "watch"}},
+	{APIGroups: []string{""}, Resources: []string{"pods/status"}, Verbs: []string{"update", "patch"}},
+	{APIGroups: []string{""}, Resources: []string{"pods/binding"}, Verbs: []string{"create"}},
+	{APIGroups: []string{"", "events.k8s.io"}, Resources: []string{"events"}, Verbs: []string{"create", "patch"}},
+	{APIGroups: []string{""}, Resources: []string{"configmaps", "persistentvolumeclaims", "replicationcontrollers"}, Verbs: []string{"get", "list", "watch"}},
+	{APIGroups: []string{""}, Resources: []string{"persistentvolumes", "services"}, Verbs: []string{"get", "list", "watch"}},
+	{APIGroups: []string{"storage.k8s.io"}, Resources: []string{"storageclasses", "volumeattachments", "csidrivers", "csinodes", "csistoragecapacities"}, Verbs: []string{"get", "list", "watch"}},
+	{APIGroups: []string{"apps"}, Resources: []string{"replicasets", "statefulsets"}, Verbs: []string{"get", "list", "watch"}},
+	{APIGroups: []string{"policy"}, Resources: []string{"poddisruptionbudgets"}, Verbs: []string{"get", "list", "watch"}},
+	{APIGroups: []string{"resource.k8s.io"}, Resources: []string{"resourceslices", "deviceclasses", "resourceclaims"}, Verbs: []string{"get", "list", "watch"}},
+	{APIGroups: []string{"coordination.k8s.io"}, Resources: []string{"leases"}, Verbs: []string{"get", "list", "watch", "create", "update", "patch"}},
+}
+
+// newRBACCommand returns the command printing the ClusterRole and
+// ClusterRoleBinding the scheduler needs for the plugin configuration, and a
+// Role and RoleBinding in each namespace of the Secrets it reads.
+func newRBACCommand() *cobra.Command {
+	var (
+		name           string
+		namespace      string
+		serviceAccount string
+		pluginArgs     string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "rbac",
+		Short: "Print the RBAC manifests required by the scheduler and the enabled plugin features",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			var args compatibilityPlugin.ImageCompatibilityPluginArgs
+			if pluginArgs != "" {
+				data, err := os.ReadFile(pluginArgs)
+				if err != nil {
+					return fmt.Errorf("failed to read plugin args: %w", err)
+				}
+				if err := sigsyaml.UnmarshalStrict(data, &args); err != nil {
+					return fmt.Errorf("failed to parse plugin args: %w", err)
+				}
+			}
+
+			labels := map[string]string{"app": name}
+			role := &rbacv1.ClusterRole{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+				ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+				Rules:      append(append([]rbacv1.PolicyRule{}, schedulerPolicyRules...), compatibilityPlugin.PolicyRules(args)...),
+			}
+			binding := &rbacv1.ClusterRoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRoleBinding"},
+				ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: name},
+				Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: serviceAccount, Namespace: namespace}},
+			}
+			objs := []interface{}{role, binding}
+
+			secretRules := compatibilityPlugin.SecretPolicyRules(args)
+			for _, ns := range slices.Sorted(maps.Keys(secretRules)) {
+				objs = append(objs,
+					&rbacv1.Role{
+						TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "Role"},
+						ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns, Labels: labels},
+						Rules:      []rbacv1.PolicyRule{secretRules[ns]},
+					},
+					&rbacv1.RoleBinding{
+						TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "RoleBinding"},
+						ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns, Labels: labels},
+						RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: name},
+						Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: serviceAccount, Namespace: namespace}},
+					})
+			}
+
+			for i, obj := range objs {
+				data, err := sigsyaml.Marshal(obj)
+				if err != nil {
+					return err
+				}
+				if i > 0 {
+					fmt.Fprintln(cmd.OutOrStdout(), "---")
+				}
+				fmt.Fprint(cmd.OutOrStdout(), string(data))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "custom-scheduler", "Name of the ClusterRole, ClusterRoleBinding and the Roles and RoleBindings for Secrets")
+	cmd.Flags().StringVar(&namespace, "namespace", "custom-scheduler", "Namespace of the scheduler service account")
+	cmd.Flags().StringVar(&serviceAccount, "service-account", "custom-scheduler", "Name of the scheduler service account")
+	cmd.Flags().StringVar(&pluginArgs, "plugin-args", "", "Path to a YAML file with the ImageCompatibilityFilter plugin args, to include the permissions of the enabled features")
+
+	return cmd
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"custom-scheduler/pkg/plugins/compatibilityPlugin"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	nfdclientset "sigs.k8s.io/node-feature-discovery/api/generated/clientset/versioned"
+)
+
+// newSimulateCommand returns the command reporting which nodes could run an
+// image, evaluated against NodeFeature objects without scheduling anything.
+func newSimulateCommand() *cobra.Command {
+	var (
+		kubeconfig string
+		image      string
+		plainHttp  bool
+		output     string
+		schedURL   string
+		runtime    string
+		perArch    bool
+		topology   string
+		minRatio   float64
+	)
+
+	cmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Report which nodes are compatible with an image without creating any resources",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			restCfg, err := compatibilityPlugin.NewRestConfig(kubeconfig)
+			if err != nil {
+				return err
+			}
+			kubeClient, err := kubernetes.NewForConfig(restCfg)
+			if err != nil {
+				return fmt.Errorf("failed to create kubernetes client: %w", err)
+			}
+			nfdClient, err := nfdclientset.NewForConfig(restCfg)
+			if err != nil {
+				return fmt.Errorf("failed to create nfd client: %w", err)
+			}
+
+			s := &compatibilityPlugin.Simulator{
+				KubeClient: kubeClient,
+				NfdClient:  nfdClient,
+				Args:       compatibilityPlugin.ImageCompatibilityPluginArgs{PlainHttp: plainHttp, MinMatchRatio: &minRatio},
+
+				RuntimeClass: runtime,
+			}
+			if topology != "" {
+				domains, err := s.SimulateTopologyDomains(cmd.Context(), image, topology)
+				if err != nil {
+					return err
+				}
+				return printDomains(image, domains, output)
+			}
+			if perArch {
+				archs, err := s.SimulateArchitectures(cmd.Context(), image)
+				if err != nil {
+					return err
+				}
+				return printArchitectures(image, archs, output)
+			}
+			compatible, incompatible, err := s.Simulate(cmd.Context(), image)
+			if err != nil {
+				return err
+			}
+
+			var cached []compatibilityPlugin.CacheEntry
+			if schedURL != "" {
+				if cached, err = fetchCacheEntries(cmd.Context(), schedURL, image); err != nil {
+					return err
+				}
+			}
+
+			switch output {
+			case "json":
+				result := map[string]interface{}{
+					"image":             image,
+					"compatibleNodes":   compatible,
+					"incompatibleNodes": incompatible,
+				}
+				if schedURL != "" {
+					result["schedulerCache"] = cached
+				}
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(result)
+			case "text":
+				fmt.Printf("Compatible nodes (%d): %s\n", len(compatible), strings.Join(compatible, ", "))
+				fmt.Printf("Incompatible nodes (%d): %s\n", len(incompatible), strings.Join(incompatible, ", "))
+				if schedURL != "" {
+					if len(cached) == 0 {
+						fmt.Println("Scheduler cache: not cached")
+					}
+					for _, entry := range cached {
+						fmt.Printf("Scheduler cache: evaluated at %s (age %s)\n",
+							entry.CachedAt.Format(time.RFC3339), time.Since(entry.CachedAt).Round(time.Second))
+					}
+				}
+				return nil
+			default:
+				return fmt.Errorf("unsupported output format %q, must be text or json", output)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file, defaults to the in-cluster config, $KUBECONFIG or ~/.kube/config")
+	cmd.Flags().StringVar(&image, "image", "", "Image reference to evaluate")
+	cmd.Flags().BoolVar(&plainHttp, "plain-http", false, "Use plain HTTP to fetch the compatibility artifact")
+	cmd.Flags().StringVar(&runtime, "runtime-class", "", "Runtime class of the pod, to evaluate the compatibility sets tagged for it")
+	cmd.Flags().BoolVar(&perArch, "per-architecture", false, "Evaluate the image variant of every node architecture against the nodes of that architecture")
+	cmd.Flags().StringVar(&topology, "topology-key", "", "Node label of topology domains, e.g. topology.kubernetes.io/zone, to report which domains can run the image")
+	cmd.Flags().Float64Var(&minRatio, "min-match-ratio", 1, "Ratio of the required compatibility sets a node must satisfy, as the minMatchRatio plugin argument")
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "Output format: text or json")
+	cmd.Flags().StringVar(&schedURL, "scheduler-url", "", "Base URL of the scheduler debug endpoints (plugin bindAddress), to also print the age of its cached evaluation")
+	_ = cmd.MarkFlagRequired("image")
+
+	return cmd
+}
+
+// printArchitectures prints the compatibility of the image variant of every
+// node architecture.
+func printArchitectures(image string, archs []compatibilityPlugin.ArchitectureCompatibility, output string) error {
+	switch output {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(map[string]interface{}{"image": image, "architectures": archs})
+	case "text":
+		for _, arch := range archs {
+			name := arch.Architecture
+			if name == "" {
+				name = "(no architecture label)"
+			}
+			if arch.Image == "" {
+				fmt.Printf("%s: no image variant, incompatible nodes (%d): %s\n",
+					name, len(arch.IncompatibleNodes), strings.Join(arch.IncompatibleNodes, ", "))
+				continue
+			}
+			fmt.Printf("%s (%s):\n", name, arch.Image)
+			fmt.Printf("  Compatible nodes (%d): %s\n", len(arch.CompatibleNodes), strings.Join(arch.CompatibleNodes, ", "))
+			fmt.Printf("  Incompatible nodes (%d): %s\n", len(arch.IncompatibleNodes), strings.Join(arch.IncompatibleNodes, ", "))
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q, must be text or json", output)
+	}
+}
+
+// printDomains prints which topology domains can run the image.
+func printDomains(image string, domains []compatibilityPlugin.DomainCompatibility, output string) error {
+	switch output {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(map[string]interface{}{"image": image, "domains": domains})
+	case "text":
+		for _, domain := range domains {
+			verdict := "incompatible"
+			if domain.Compatible {
+				verdict = "compatible"
+			}
+			fmt.Printf("%s: %s (%d nodes: %s)\n", domain.Domain, verdict, len(domain.Nodes), strings.Join(domain.Nodes, ", "))
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q, must be text or json", output)
+	}
+}
+
+// fetchCacheEntries returns the scheduler's cached evaluation of the image
+// from its /debug/cache endpoint.
+func fetchCacheEntries(ctx context.Context, schedURL, image string) ([]compatibilityPlugin.CacheEntry, error) {
+	reqURL := strings.TrimSuffix(schedURL, "/") + "/debug/cache?image=" + url.QueryEscape(image)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scheduler cache: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to query scheduler cache: %s", resp.Status)
+	}
+
+	var entries []compatibilityPlugin.CacheEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("failed to decode scheduler cache: %w", err)
+	}
+	return entries, nil
+}